@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagChartIndex      = ""
+	flagChartOCI        = ""
+	flagChartName       = ""
+	flagChartAppVersion = true
+	flagChartDryRun     = false
+	flagChartLatest     = false
+)
+
+var chartCmd = &cobra.Command{
+	Use:   "chart <flags> [chart-dir]",
+	Short: "Sets the version of a Helm chart",
+	Long: `Sets 'version' and 'appVersion' in the Chart.yaml of a Helm chart to the
+current version, keeping the formatting of the file.
+
+With --index or --oci, the existing versions of the chart are read from a chart
+repository index.yaml (a local file or a URL) or from an OCI repository. The
+command fails if the version already exists. With --print-latest, it also
+prints 'latest' after the version if it's higher than all existing versions.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagPreRelease != "" && flagReleaseOnly {
+			return errors.New("Asked for a pre-release version, but the --release flag is on.")
+		}
+
+		if flagChartIndex != "" && flagChartOCI != "" {
+			return errors.New("--index and --oci are mutually exclusive")
+		}
+
+		if flagChartLatest && flagChartIndex == "" && flagChartOCI == "" {
+			return errors.New("--print-latest requires --index or --oci")
+		}
+
+		version, err := currentVersion(flagReleaseOnly)
+		if err != nil {
+			return err
+		}
+
+		if err := releaseChecks(version); err != nil {
+			return err
+		}
+
+		if flagPreRelease != "" {
+			version = sver.PreRelease(version, flagPreRelease)
+		}
+
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		chartName := flagChartName
+		if chartName == "" {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				return err
+			}
+			chartName = filepath.Base(abs)
+		}
+
+		var existing []string
+		switch {
+		case flagChartIndex != "":
+			existing, err = sver.ChartIndexVersions(cmd.Context(), flagChartIndex, chartName)
+		case flagChartOCI != "":
+			existing, err = sver.OCIChartVersions(flagChartOCI, registryOptions()...)
+		}
+		if err != nil {
+			return err
+		}
+
+		exists, err := sver.VersionExists(version, existing)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return errors.Wrapf(sver.ErrVersionExists, "can't publish %s of chart '%s'", version, chartName)
+		}
+
+		if !flagChartDryRun {
+			appVersion := ""
+			if flagChartAppVersion {
+				appVersion = version
+			}
+
+			if err := sver.UpdateChart(filepath.Join(dir, "Chart.yaml"), version, appVersion); err != nil {
+				return err
+			}
+		}
+
+		fmt.Println(version)
+
+		if flagChartLatest {
+			latest, err := sver.IsLatestVersion(version, existing)
+			if err != nil {
+				return err
+			}
+
+			if latest {
+				fmt.Println("latest")
+			}
+		}
+
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
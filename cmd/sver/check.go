@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagCheckConstraint         = ""
+	flagCheckIncludePreReleases = false
+	flagCompareOp               = ""
+)
+
+// errFalse makes a command exit with status 1 without printing an error, after
+// it printed a negative result.
+var errFalse = errors.New("false")
+
+var checkCmd = &cobra.Command{
+	Use:   "check <flags> [version]",
+	Short: "Checks whether a version matches a constraint",
+	Long: `Prints 'true' and exits with status 0 if the version matches the constraint,
+or prints 'false' and exits with status 1 if it doesn't. The version defaults
+to the current version.
+
+Constraints are comma or space separated comparators, like '>=1.4, <2', with
+'||' between alternatives. Pre-release versions only match if a comparator is a
+pre-release of the same X.Y.Z, unless --include-pre-releases is set.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagCheckConstraint == "" {
+			return errors.New("--constraint is required")
+		}
+
+		constraint, err := sver.ParseConstraint(flagCheckConstraint)
+		if err != nil {
+			return err
+		}
+
+		version, err := versionArg(args)
+		if err != nil {
+			return err
+		}
+
+		ok, err := constraint.Check(version, flagCheckIncludePreReleases)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(ok)
+		if !ok {
+			return errFalse
+		}
+
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <flags> [a] <b>",
+	Short: "Compares two versions",
+	Long: `Prints -1, 0 or 1 if version a is lower than, equal to or greater than
+version b, by semantic version precedence. Version a defaults to the current
+version.
+
+With --op, prints 'true' and exits with status 0 if the comparison holds, or
+prints 'false' and exits with status 1 if it doesn't. Operators are 'eq', 'ne',
+'gt', 'ge', 'lt' and 'le'.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a, err := versionArg(args[:len(args)-1])
+		if err != nil {
+			return err
+		}
+
+		result, err := sver.CompareVersions(a, args[len(args)-1])
+		if err != nil {
+			return err
+		}
+
+		if flagCompareOp == "" {
+			fmt.Println(result)
+			return nil
+		}
+
+		var ok bool
+		switch flagCompareOp {
+		case "eq":
+			ok = result == 0
+		case "ne":
+			ok = result != 0
+		case "gt":
+			ok = result > 0
+		case "ge":
+			ok = result >= 0
+		case "lt":
+			ok = result < 0
+		case "le":
+			ok = result <= 0
+		default:
+			return errors.Errorf("unknown operator '%s'; supported operators are 'eq', 'ne', 'gt', 'ge', 'lt' and 'le'", flagCompareOp)
+		}
+
+		fmt.Println(ok)
+		if !ok {
+			return errFalse
+		}
+
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// versionArg returns the version passed as argument, or the current version.
+func versionArg(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	version, err := currentVersion(false)
+	if err != nil {
+		return "", err
+	}
+
+	if flagPreRelease != "" {
+		version = sver.PreRelease(version, flagPreRelease)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigFile = ".sver.yaml"
+
+// config is the optional sver configuration file.
+type config struct {
+	Registries []registryConfig   `yaml:"registries"`
+	Tags       tagsConfig         `yaml:"tags"`
+	Stamp      []sver.StampTarget `yaml:"stamp"`
+}
+
+// registryConfig is a registry that `sver tags` publishes to. Secrets are read
+// from environment variables so they never have to be stored in the file.
+type registryConfig struct {
+	Server      string `yaml:"server"`
+	Repository  string `yaml:"repository"`
+	Username    string `yaml:"username"`
+	PasswordEnv string `yaml:"password-env"`
+	TokenEnv    string `yaml:"token-env"`
+}
+
+// tagsConfig sets the floating-tag policy of `sver tags`, for all images or for
+// specific images, and the variants images are built in.
+type tagsConfig struct {
+	Policy   sver.TagPolicy             `yaml:"policy"`
+	Variants []string                   `yaml:"variants"`
+	Images   map[string]imageTagsConfig `yaml:"images"`
+}
+
+type imageTagsConfig struct {
+	Policy sver.TagPolicy `yaml:"policy"`
+}
+
+// tagPolicy returns the policy of an image. A nil policy means the default one.
+func (c *config) tagPolicy(image string) sver.TagPolicy {
+	if imageConfig, ok := c.Tags.Images[image]; ok && imageConfig.Policy != nil {
+		return imageConfig.Policy
+	}
+
+	return c.Tags.Policy
+}
+
+// loadConfig reads the config file set with --config, or the default config
+// file if it exists.
+func loadConfig() (*config, error) {
+	path := flagConfig
+	if path == "" {
+		if _, err := os.Stat(defaultConfigFile); os.IsNotExist(err) {
+			return &config{}, nil
+		}
+		path = defaultConfigFile
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read config file [%s]", path)
+	}
+
+	cfg := &config{}
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse config file [%s]", path)
+	}
+
+	for i, registry := range cfg.Registries {
+		if registry.Server == "" {
+			return nil, errors.Errorf("registry #%d in config file [%s] has no server", i+1, path)
+		}
+	}
+
+	if err := cfg.Tags.Policy.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid tag policy in config file [%s]", path)
+	}
+
+	for image, imageConfig := range cfg.Tags.Images {
+		if err := imageConfig.Policy.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid tag policy for image [%s] in config file [%s]", image, path)
+		}
+	}
+
+	for _, target := range cfg.Stamp {
+		if _, err := target.Updater(); err != nil {
+			return nil, errors.Wrapf(err, "invalid stamp target in config file [%s]", path)
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes tell scripts why sver failed. A negative result, like a version
+// that doesn't match a constraint, exits with exitError too.
+const (
+	exitError          = 1
+	exitUsage          = 2
+	exitNoGit          = 3
+	exitNotSemver      = 4
+	exitNotOnTag       = 5
+	exitDirty          = 6
+	exitVersionExists  = 7
+	exitModulePath     = 8
+	exitRegistryAuth   = 9
+	exitRegistryLimits = 10
+	exitUnknownRef     = 11
+)
+
+// usageError is an invalid flag.
+type usageError struct {
+	error
+}
+
+func (e usageError) Unwrap() error {
+	return e.error
+}
+
+func flagError(_ *cobra.Command, err error) error {
+	return usageError{err}
+}
+
+// exitCode returns the exit code for an error returned by a command.
+func exitCode(err error) int {
+	var (
+		usage     usageError
+		notSemver *sver.ErrNotSemver
+		rateLimit *sver.RateLimitError
+	)
+
+	switch {
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.Is(err, sver.ErrNoGit):
+		return exitNoGit
+	case errors.As(err, &notSemver):
+		return exitNotSemver
+	case errors.Is(err, sver.ErrNotOnTag):
+		return exitNotOnTag
+	case errors.Is(err, sver.ErrDirty):
+		return exitDirty
+	case errors.Is(err, sver.ErrVersionExists):
+		return exitVersionExists
+	case errors.Is(err, sver.ErrModulePath):
+		return exitModulePath
+	case errors.Is(err, sver.ErrRegistryAuth):
+		return exitRegistryAuth
+	case errors.As(err, &rateLimit):
+		return exitRegistryLimits
+	case errors.Is(err, sver.ErrUnknownRef):
+		return exitUnknownRef
+	}
+
+	return exitError
+}
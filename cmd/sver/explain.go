@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+)
+
+// explainVersion prints how the current version was derived to stderr, so
+// the output stays usable in scripts.
+func explainVersion(d *sver.Derivation) {
+	fmt.Fprintln(os.Stderr, "current version:")
+	explainSteps(d.Steps)
+}
+
+func explainNext(d *sver.NextDerivation) {
+	fmt.Fprintf(os.Stderr, "next %s version:\n", d.Type)
+	explainSteps(d.Steps)
+}
+
+// explainTags prints the decision for each tag. The repository is empty when
+// the tags are the same for all registries.
+func explainTags(repository string, d *sver.TagsDerivation) {
+	if repository == "" {
+		fmt.Fprintln(os.Stderr, "tags:")
+	} else {
+		fmt.Fprintf(os.Stderr, "tags for %s:\n", repository)
+	}
+
+	for _, decision := range d.Decisions {
+		fmt.Fprintf(os.Stderr, "  %s\n", decision)
+	}
+}
+
+func explainSteps(steps []string) {
+	for _, step := range steps {
+		fmt.Fprintf(os.Stderr, "  %s\n", step)
+	}
+}
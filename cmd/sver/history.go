@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagHistoryRange       = ""
+	flagHistoryFirstParent = false
+	flagHistoryOutput      = "csv"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <flags>",
+	Short: "Prints the version of each commit of a range",
+	Long: `Prints the version sver derives at each commit of a range, newest first,
+with the commit, the date of the commit in UTC, the version and the release
+tag of the commit. The versions are derived in a single pass over the commit
+graph, so this is fast on large repositories.
+
+Tags that aren't semantic versions, and tags of nested modules, are skipped.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagHistoryOutput != "csv" && flagHistoryOutput != "json" {
+			return errors.Errorf("unknown output format '%s'; supported formats are 'csv' and 'json'", flagHistoryOutput)
+		}
+
+		entries, err := sver.History(sver.HistoryOptions{
+			Range:       flagHistoryRange,
+			FirstParent: flagHistoryFirstParent,
+			Date:        flagDate,
+		})
+		if err != nil {
+			return err
+		}
+
+		if flagHistoryOutput == "json" {
+			out, err := json.MarshalIndent(entries, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		w := csv.NewWriter(os.Stdout)
+		if err := w.Write([]string{"commit", "date", "version", "tag"}); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := w.Write([]string{entry.Commit, entry.Date, entry.Version, entry.Tag}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+
+		return w.Error()
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagLDFlagsVar       = ""
+	flagLDFlagsCommitVar = ""
+	flagLDFlagsDateVar   = ""
+	flagLDFlagsWrite     = ""
+	flagLDFlagsPackage   = ""
+)
+
+var ldflagsCmd = &cobra.Command{
+	Use:   "ldflags <flags>",
+	Short: "Prints the Go linker flags that embed the version in a binary",
+	Long: `Prints the value of the -ldflags argument of 'go build' that sets the version,
+the full commit hash and the commit date in Go variables, like:
+
+  go build -ldflags "$(sver ldflags --var main.version --commit-var main.commit --date-var main.date)"
+
+The date is the date of the commit in UTC, so builds of the same commit are
+reproducible. Like in development versions, --date chooses the committer or
+the author date, and SOURCE_DATE_EPOCH overrides it.
+
+With --write, a Go file that sets the variables in an init function is written
+instead, for use with 'go generate'. The package of the file is --package, which
+defaults to $GOPACKAGE, set by 'go generate':
+
+  //go:generate sver ldflags --var version --commit-var commit --write version_gen.go`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := currentVersion(false)
+		if err != nil {
+			return err
+		}
+
+		if flagPreRelease != "" {
+			version = sver.PreRelease(version, flagPreRelease)
+		}
+
+		info, err := sver.DeriveBuildInfo(version, versionOptions(false))
+		if err != nil {
+			return err
+		}
+
+		vars := sver.LinkerVars{
+			Version: flagLDFlagsVar,
+			Commit:  flagLDFlagsCommitVar,
+			Date:    flagLDFlagsDateVar,
+		}
+
+		if flagLDFlagsWrite == "" {
+			fmt.Println(info.LDFlags(vars))
+			return nil
+		}
+
+		src, err := info.GoSource(flagLDFlagsPackage, vars)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(flagLDFlagsWrite, src, 0644) // nolint: gosec // generated source files are world readable
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagLintTagsTagPrefix = ""
+	flagLintTagsOutput    = "text"
+)
+
+var lintTagsCmd = &cobra.Command{
+	Use:   "lint-tags <flags>",
+	Short: "Checks the semver tags of the repository",
+	Long: `Checks the semver tags of the repository and reports:
+  invalid:    tags that aren't semantic versions
+  duplicate:  versions tagged both with and without the 'v' prefix
+  regression: tags lower than a tag of one of the ancestors of their commit
+  gap:        releases that skip versions, like 1.2.5 after 1.2.3
+
+Exits with status 1 if there's a problem. Without --tag-prefix, tags that
+contain a '/', like the tags of nested Go modules, are skipped.
+
+With --release, sver fails on the problems of the tags of HEAD.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems, err := sver.LintTags(sver.LintOptions{Prefix: flagLintTagsTagPrefix})
+		if err != nil {
+			return err
+		}
+
+		switch flagLintTagsOutput {
+		case "text":
+			for _, problem := range problems {
+				fmt.Println(problem)
+			}
+		case "json":
+			out, err := json.MarshalIndent(problems, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+		default:
+			return errors.Errorf("unknown output format '%s'; supported formats are 'text' and 'json'", flagLintTagsOutput)
+		}
+
+		if len(problems) > 0 {
+			return errFalse
+		}
+
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
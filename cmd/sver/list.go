@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagListTagPrefix          = ""
+	flagListSince              = ""
+	flagListConstraint         = ""
+	flagListIncludePreReleases = false
+	flagListLatestPer          = ""
+	flagListOutput             = "text"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list <flags>",
+	Short: "Lists the semver tags of the repository",
+	Long: `Lists the semver tags of the repository, sorted by precedence, with their
+commit, the date of the commit, and whether they're reachable from HEAD.
+Tags that aren't semantic versions are skipped.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := sver.ListOptions{
+			Prefix:             flagListTagPrefix,
+			Since:              flagListSince,
+			IncludePreReleases: flagListIncludePreReleases,
+			LatestPer:          flagListLatestPer,
+		}
+
+		if flagListConstraint != "" {
+			constraint, err := sver.ParseConstraint(flagListConstraint)
+			if err != nil {
+				return err
+			}
+			opts.Constraint = constraint
+		}
+
+		tags, err := sver.ListTags(opts)
+		if err != nil {
+			return err
+		}
+
+		switch flagListOutput {
+		case "text":
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			for _, tag := range tags {
+				reachable := "reachable"
+				if !tag.Reachable {
+					reachable = "unreachable"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%.8s\t%s\t%s\n", tag.Version, tag.Name, tag.Commit, tag.Date, reachable)
+			}
+			return w.Flush()
+		case "json":
+			out, err := json.MarshalIndent(tags, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+		default:
+			return errors.Errorf("unknown output format '%s'; supported formats are 'text' and 'json'", flagListOutput)
+		}
+
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
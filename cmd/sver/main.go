@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/aserto-dev/sver/pkg/sver"
 	"github.com/aserto-dev/sver/pkg/version"
@@ -19,10 +21,14 @@ var (
 	flagForce       = false
 	flagReleaseOnly = false
 	flagPrefix      = false
+	flagCheckGoMod  = false
+	flagExplain     = false
+	flagRef         = ""
+	flagDate        = ""
 
-	flagTagsServerURL = ""
-	flagTagsUsername  = ""
-	flagTagsPassword  = ""
+	flagConfig = ""
+
+	flagVersionOutput = "text"
 )
 
 var rootCmd = &cobra.Command{
@@ -32,7 +38,11 @@ var rootCmd = &cobra.Command{
 			return errors.New("Asked for a pre-release version, but the --release flag is on.")
 		}
 
-		version, err := sver.CurrentVersion(flagReleaseOnly, flagForce)
+		if flagRef != "" && flagCheckGoMod {
+			return errors.New("--check-go-mod reads the go.mod files of the working tree, so it can't be used with --ref")
+		}
+
+		version, err := currentVersion(flagReleaseOnly)
 		if err != nil {
 			return err
 		}
@@ -41,11 +51,37 @@ var rootCmd = &cobra.Command{
 			version = sver.PreRelease(version, flagPreRelease)
 		}
 
+		if flagNext == "suggest" {
+			nextType, changes, err := sver.SuggestNext(version, versionOptions(false))
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "suggesting a %s version\n", nextType)
+			for _, change := range changes {
+				kind := "incompatible"
+				if change.Compatible {
+					kind = "compatible"
+				}
+				fmt.Fprintf(os.Stderr, "  %s: %s\n", kind, change)
+			}
+
+			flagNext = nextType
+		}
+
 		if flagNext != "" {
-			version, err = sver.Next(version, flagNext)
+			next, err := sver.DeriveNext(version, flagNext, versionOptions(false))
+			if flagExplain {
+				explainNext(next)
+			}
 			if err != nil {
 				return err
 			}
+			version = next.Version
+		}
+
+		if err := releaseChecks(version); err != nil {
+			return err
 		}
 
 		if flagMinorOnly && flagMajorOnly {
@@ -86,63 +122,81 @@ var rootCmd = &cobra.Command{
 	SilenceUsage:  true,
 }
 
-var versionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "Print version and exit",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("sver %s\n", version.GetInfo().String())
-	},
-	SilenceErrors: true,
-	SilenceUsage:  true,
-}
-
-var tagsCmd = &cobra.Command{
-	Use:   "tags <flags> [image]",
-	Short: "Prints the tags that should be pushed to a docker registry",
-	Long: `Connects to a docker registry and lists all tags for an image.
-Depending on whether the current version is a development version and if
-it's the latest one, it returns the appropriate tags to be pushed.`,
-	Args: cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		if flagPreRelease != "" && flagReleaseOnly {
-			return errors.New("Asked for a pre-release version, but the --release flag is on.")
-		}
-
-		version, err := sver.CurrentVersion(flagReleaseOnly, flagForce)
-		if err != nil {
+// releaseChecks checks that the module paths in go.mod match the version, with
+// --release or --check-go-mod, and that the tags of --ref have no problems
+// reported by lint-tags, with --release.
+func releaseChecks(version string) error {
+	// The go.mod files of an older ref were checked when it was released.
+	if (flagReleaseOnly && flagRef == "") || flagCheckGoMod {
+		if err := sver.CheckGoModules(version, sver.GoModuleOptions{}); err != nil {
 			return err
 		}
+	}
 
-		if flagPreRelease != "" {
-			version = sver.PreRelease(version, flagPreRelease)
-		}
+	if !flagReleaseOnly {
+		return nil
+	}
 
-		serverURL, err := url.Parse(flagTagsServerURL)
-		if err != nil {
-			return err
-		}
+	problems, err := sver.RefTagProblems(sver.LintOptions{Ref: flagRef})
+	if err != nil {
+		return err
+	}
 
-		host := flagTagsServerURL
-		if serverURL.Host != "" {
-			host = serverURL.Host
+	if len(problems) > 0 {
+		messages := []string{}
+		for _, problem := range problems {
+			messages = append(messages, problem.String())
 		}
+		return errors.Errorf("the release tag has problems:\n%s", strings.Join(messages, "\n"))
+	}
 
-		existingTags, err := sver.ImageTags(host+"/"+args[0], flagTagsUsername, flagTagsPassword)
-		if err != nil {
-			return err
-		}
+	return nil
+}
 
-		tags, err := sver.CalculateTagsForVersion(version, existingTags)
-		if err != nil {
-			return err
-		}
+// versionOptions returns the options of the flags shared by the commands that
+// derive a version.
+func versionOptions(releaseOnly bool) sver.Options {
+	return sver.Options{
+		Ref:             flagRef,
+		Date:            flagDate,
+		SourceDateEpoch: os.Getenv(sver.SourceDateEpochEnv),
+		ReleaseOnly:     releaseOnly,
+		Force:           flagForce,
+		Explain:         flagExplain,
+	}
+}
 
-		for _, tag := range tags {
-			if flagPrefix {
-				fmt.Println("v" + tag)
-			} else {
-				fmt.Println(tag)
+// currentVersion returns the version at --ref, and explains how it was derived
+// with --explain, even if that fails.
+func currentVersion(releaseOnly bool) (string, error) {
+	d, err := sver.DeriveVersion(versionOptions(releaseOnly))
+	if flagExplain {
+		explainVersion(d)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return d.Version, nil
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version and exit",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info := version.GetInfo()
+
+		switch flagVersionOutput {
+		case "text":
+			fmt.Printf("sver %s\n", info.String())
+		case "json":
+			out, err := json.MarshalIndent(info, "", "  ")
+			if err != nil {
+				return err
 			}
+			fmt.Println(string(out))
+		default:
+			return errors.Errorf("unknown output format '%s'; supported formats are 'text' and 'json'", flagVersionOutput)
 		}
 
 		return nil
@@ -152,26 +206,137 @@ it's the latest one, it returns the appropriate tags to be pushed.`,
 }
 
 func main() {
-	rootCmd.Flags().StringVarP(&flagNext, "next", "n", "", "Prints the next version. Possible values are 'major', 'minor' or 'patch'.")
+	rootCmd.Flags().StringVarP(&flagNext, "next", "n", "", "Prints the next version. Possible values are 'major', 'minor', 'patch' or 'suggest', which picks one from the changes to the exported Go API since the last tag.")
 	rootCmd.Flags().StringVarP(&flagPreRelease, "pre-release", "", os.ExpandEnv("${PRE_RELEASE}"), `Adds a pre release identifier to the version. (env "PRE_RELEASE")`)
 	rootCmd.Flags().BoolVarP(&flagMajorOnly, "major-only", "m", false, "Only prints the major version. Fails if version is a development version.")
 	rootCmd.Flags().BoolVarP(&flagMinorOnly, "minor-only", "r", false, "Only prints the major and minor versions. Fails if version is a development version.")
-	rootCmd.Flags().BoolVarP(&flagReleaseOnly, "release", "", false, "Fail if this is a dev, pre-release or dirty version.")
+	rootCmd.Flags().BoolVarP(&flagReleaseOnly, "release", "", false, "Fail if this is a dev, pre-release or dirty version, or if the tag has problems reported by lint-tags.")
 	rootCmd.Flags().BoolVarP(&flagForce, "force", "f", false, "Ignore a dirty repository.")
 	rootCmd.Flags().BoolVarP(&flagPrefix, "prefix", "p", false, "Add the 'v' prefix to the output version.")
+	rootCmd.Flags().BoolVarP(&flagExplain, "explain", "v", false, "Explain on stderr how the version was derived.")
+	rootCmd.Flags().StringVarP(&flagRef, "ref", "", "", "Derive the version at a commit, a branch or a tag instead of HEAD. The tree isn't checked for uncommitted changes.")
+	rootCmd.Flags().StringVarP(&flagDate, "date", "", "committer", "Date of the commit in development versions, 'committer' or 'author'. SOURCE_DATE_EPOCH overrides it.")
+	rootCmd.Flags().BoolVarP(&flagCheckGoMod, "check-go-mod", "", false, "Fail if the major version doesn't match the module path in go.mod, or in nested modules. Always on with --release.")
+
+	versionCmd.Flags().StringVarP(&flagVersionOutput, "output", "o", "text", "Output format, 'text' or 'json'.")
 
-	tagsCmd.Flags().StringVarP(&flagTagsServerURL, "server", "s", "https://registry-1.docker.io/", "Registry server to connect to.")
-	tagsCmd.Flags().StringVarP(&flagTagsUsername, "user", "u", "", "Username for the registry.")
-	tagsCmd.Flags().StringVarP(&flagTagsPassword, "password", "p", "", "Password for the registry.")
+	rootCmd.PersistentFlags().StringVarP(&flagConfig, "config", "c", "", fmt.Sprintf("Path to the sver config file. (default %q if it exists)", defaultConfigFile))
+
+	tagsCmd.Flags().StringArrayVarP(&flagTagsServers, "server", "s", []string{"https://registry-1.docker.io/"}, "Registry server to connect to. Can be repeated to publish to several registries.")
+	tagsCmd.Flags().StringVarP(&flagTagsUsername, "user", "u", "", `Username for the registry. (env "REGISTRY_USERNAME")`)
+	tagsCmd.Flags().StringVarP(&flagTagsPassword, "password", "p", "", `Password for the registry. Prefer --password-stdin. (env "REGISTRY_PASSWORD")`)
+	tagsCmd.Flags().BoolVarP(&flagTagsPasswordStdin, "password-stdin", "", false, "Read the registry password from stdin.")
+	tagsCmd.Flags().StringVarP(&flagTagsToken, "token", "t", "", `Bearer token for the registry. (env "REGISTRY_TOKEN")`)
+	tagsCmd.Flags().StringVarP(&flagTagsSource, "source", "", "", "Image reference to push to the computed tags.")
+	tagsCmd.Flags().BoolVarP(&flagTagsPush, "push", "", false, "Push the --source image to all computed tags.")
+	tagsCmd.Flags().BoolVarP(&flagTagsDryRun, "dry-run", "", false, "Show what --push would do without pushing anything.")
+	tagsCmd.Flags().BoolVarP(&flagTagsPerRegistry, "per-registry", "", false, "Calculate tags for each registry from its own existing tags, instead of from the union of all registries.")
+	tagsCmd.Flags().BoolVarP(&flagTagsChannels, "channels", "", false, "Add floating tags for pre-release channels, like '1.4.0-rc', '1.4-rc' and 'rc' for '1.4.0-rc.3'.")
+	tagsCmd.Flags().BoolVarP(&flagTagsEdge, "edge", "", false, "Add the 'edge' tag if the version is the newest, including pre-releases.")
+	tagsCmd.Flags().StringArrayVarP(&flagTagsRules, "tag-rule", "", []string{}, "Floating tag for releases, as <condition>=<template>, like 'latest-in-minor=v{{.Major}}.{{.Minor}}'. Can be repeated and replaces the default 'X.Y', 'X' and 'latest' tags.")
+	tagsCmd.Flags().StringVarP(&flagTagsVariant, "variant", "", "", "Image variant, like 'alpine'. Tags are calculated from the existing tags of the variant only, and get the '-<variant>' suffix.")
+	tagsCmd.Flags().BoolVarP(&flagTagsVerbose, "verbose", "", false, "Log registry requests to stderr.")
+	tagsCmd.Flags().IntVarP(&flagTagsRetries, "retries", "", 5, "How many times to retry registry requests that fail with a transient error, a 429 or a 5xx status.")
+	tagsCmd.Flags().IntVarP(&flagTagsPageSize, "page-size", "", 0, "How many tags to request per page when listing existing tags. By default, the registry decides.")
+	tagsCmd.Flags().StringVarP(&flagTagsExistingPrefix, "existing-prefix", "", "", "Only consider existing tags that start with this prefix.")
+	tagsCmd.Flags().StringVarP(&flagTagsExistingFile, "existing-tags-file", "", "", "Read the existing tags from a file, or from stdin with '-', instead of from the registry. One tag per line, or JSON from 'crane ls' or 'skopeo list-tags'.")
+	tagsCmd.Flags().BoolVarP(&flagExplain, "explain", "v", false, "Explain on stderr how the version and each tag were derived.")
+	tagsCmd.Flags().BoolVarP(&flagReleaseOnly, "release", "", false, "Fail if this is a dev, pre-release or dirty version, if the tag has problems reported by lint-tags, or if the major version doesn't match the module path in go.mod.")
+	tagsCmd.Flags().BoolVarP(&flagForce, "force", "f", false, "Ignore a dirty repository.")
+	tagsCmd.Flags().BoolVarP(&flagCheckGoMod, "check-go-mod", "", false, "Fail if the major version doesn't match the module path in go.mod, or in nested modules. Always on with --release.")
+	tagsCmd.Flags().StringVarP(&flagRef, "ref", "", "", "Calculate the tags of the version at a commit, a branch or a tag instead of HEAD.")
+	tagsCmd.Flags().StringVarP(&flagDate, "date", "", "committer", "Date of the commit in development versions, 'committer' or 'author'. SOURCE_DATE_EPOCH overrides it.")
 	tagsCmd.Flags().StringVarP(&flagPreRelease, "pre-release", "", os.ExpandEnv("${PRE_RELEASE}"), `Adds a pre release identifier to the version. (env "PRE_RELEASE")`)
 
+	chartCmd.Flags().StringVarP(&flagChartIndex, "index", "", "", "Chart repository index.yaml, as a local file or a URL, to read existing versions from.")
+	chartCmd.Flags().StringVarP(&flagChartOCI, "oci", "", "", "OCI repository of the chart, like 'ghcr.io/org/charts/name', to read existing versions from.")
+	chartCmd.Flags().StringVarP(&flagChartName, "name", "", "", "Name of the chart in the index. (default name of the chart directory)")
+	chartCmd.Flags().BoolVarP(&flagChartAppVersion, "app-version", "", true, "Also set appVersion.")
+	chartCmd.Flags().BoolVarP(&flagChartDryRun, "dry-run", "", false, "Don't update Chart.yaml.")
+	chartCmd.Flags().BoolVarP(&flagChartLatest, "print-latest", "", false, "Print 'latest' after the version if it's higher than all existing versions. Requires --index or --oci.")
+	chartCmd.Flags().BoolVarP(&flagReleaseOnly, "release", "", false, "Fail if this is a dev, pre-release or dirty version, if the tag has problems reported by lint-tags, or if the major version doesn't match the module path in go.mod.")
+	chartCmd.Flags().BoolVarP(&flagForce, "force", "f", false, "Ignore a dirty repository.")
+	chartCmd.Flags().StringVarP(&flagDate, "date", "", "committer", "Date of the commit in development versions, 'committer' or 'author'. SOURCE_DATE_EPOCH overrides it.")
+	chartCmd.Flags().StringVarP(&flagPreRelease, "pre-release", "", os.ExpandEnv("${PRE_RELEASE}"), `Adds a pre release identifier to the version. (env "PRE_RELEASE")`)
+
+	stampCmd.Flags().BoolVarP(&flagStampCheck, "check", "", false, "Don't write any file, and fail if a file is out of date.")
+	stampCmd.Flags().BoolVarP(&flagForce, "force", "f", false, "Ignore a dirty repository.")
+	stampCmd.Flags().StringVarP(&flagDate, "date", "", "committer", "Date of the commit in development versions, 'committer' or 'author'. SOURCE_DATE_EPOCH overrides it.")
+	stampCmd.Flags().StringVarP(&flagPreRelease, "pre-release", "", os.ExpandEnv("${PRE_RELEASE}"), `Adds a pre release identifier to the version. (env "PRE_RELEASE")`)
+
+	ldflagsCmd.Flags().StringVarP(&flagLDFlagsVar, "var", "", "main.version", "Variable that receives the version.")
+	ldflagsCmd.Flags().StringVarP(&flagLDFlagsCommitVar, "commit-var", "", "", "Variable that receives the full commit hash.")
+	ldflagsCmd.Flags().StringVarP(&flagLDFlagsDateVar, "date-var", "", "", "Variable that receives the commit date, in RFC 3339 format.")
+	ldflagsCmd.Flags().StringVarP(&flagLDFlagsWrite, "write", "w", "", "Write a Go file that sets the variables, instead of printing linker flags.")
+	ldflagsCmd.Flags().StringVarP(&flagLDFlagsPackage, "package", "", os.ExpandEnv("${GOPACKAGE}"), `Package of the file written with --write. (env "GOPACKAGE")`)
+	ldflagsCmd.Flags().BoolVarP(&flagForce, "force", "f", false, "Ignore a dirty repository.")
+	ldflagsCmd.Flags().StringVarP(&flagDate, "date", "", "committer", "Date of the commit in development versions, 'committer' or 'author'. SOURCE_DATE_EPOCH overrides it.")
+	ldflagsCmd.Flags().StringVarP(&flagPreRelease, "pre-release", "", os.ExpandEnv("${PRE_RELEASE}"), `Adds a pre release identifier to the version. (env "PRE_RELEASE")`)
+
+	checkCmd.Flags().StringVarP(&flagCheckConstraint, "constraint", "", "", "Version constraint, like '>=1.4, <2'.")
+	checkCmd.Flags().BoolVarP(&flagCheckIncludePreReleases, "include-pre-releases", "", false, "Also match a pre-release version if its release matches the constraint.")
+	checkCmd.Flags().BoolVarP(&flagForce, "force", "f", false, "Ignore a dirty repository.")
+	checkCmd.Flags().StringVarP(&flagDate, "date", "", "committer", "Date of the commit in development versions, 'committer' or 'author'. SOURCE_DATE_EPOCH overrides it.")
+	checkCmd.Flags().StringVarP(&flagPreRelease, "pre-release", "", os.ExpandEnv("${PRE_RELEASE}"), `Adds a pre release identifier to the version. (env "PRE_RELEASE")`)
+
+	compareCmd.Flags().StringVarP(&flagCompareOp, "op", "", "", "Comparison that must hold: 'eq', 'ne', 'gt', 'ge', 'lt' or 'le'.")
+	compareCmd.Flags().BoolVarP(&flagForce, "force", "f", false, "Ignore a dirty repository.")
+	compareCmd.Flags().StringVarP(&flagDate, "date", "", "committer", "Date of the commit in development versions, 'committer' or 'author'. SOURCE_DATE_EPOCH overrides it.")
+	compareCmd.Flags().StringVarP(&flagPreRelease, "pre-release", "", os.ExpandEnv("${PRE_RELEASE}"), `Adds a pre release identifier to the version. (env "PRE_RELEASE")`)
+
+	listCmd.Flags().StringVarP(&flagListTagPrefix, "tag-prefix", "", "", "Only list tags with this prefix, like 'tools/'. The prefix is removed from the versions.")
+	listCmd.Flags().StringVarP(&flagListSince, "since", "", "", "Only list versions greater than or equal to this one.")
+	listCmd.Flags().StringVarP(&flagListConstraint, "constraint", "", "", "Only list versions that match this constraint, like '>=1.4, <2'.")
+	listCmd.Flags().BoolVarP(&flagListIncludePreReleases, "include-pre-releases", "", false, "Also list pre-release versions whose release matches --constraint.")
+	listCmd.Flags().StringVarP(&flagListLatestPer, "latest-per", "", "", "Only list the latest version of each 'major' or 'minor' series.")
+	listCmd.Flags().StringVarP(&flagListOutput, "output", "o", "text", "Output format, 'text' or 'json'.")
+
+	lintTagsCmd.Flags().StringVarP(&flagLintTagsTagPrefix, "tag-prefix", "", "", "Only check tags with this prefix, like 'tools/'.")
+	lintTagsCmd.Flags().StringVarP(&flagLintTagsOutput, "output", "o", "text", "Output format, 'text' or 'json'.")
+
+	historyCmd.Flags().StringVarP(&flagHistoryRange, "range", "", "HEAD", "Commits to print, like 'v1.0.0..main', or a ref for all of its history.")
+	historyCmd.Flags().BoolVarP(&flagHistoryFirstParent, "first-parent", "", false, "Only print the commits of the first-parent chain, like the merges into a main branch.")
+	historyCmd.Flags().StringVarP(&flagDate, "date", "", "committer", "Date of the commits, 'committer' or 'author'.")
+	historyCmd.Flags().StringVarP(&flagHistoryOutput, "output", "o", "csv", "Output format, 'csv' or 'json'.")
+
+	sourceDateEpochCmd.Flags().StringVarP(&flagRef, "ref", "", "", "Print the date of a commit, a branch or a tag instead of HEAD. SOURCE_DATE_EPOCH is ignored.")
+	sourceDateEpochCmd.Flags().StringVarP(&flagDate, "date", "", "committer", "Date of the commit, 'committer' or 'author'.")
+	sourceDateEpochCmd.Flags().StringVarP(&flagSourceDateEpochOutput, "output", "o", "unix", "Output format, 'unix' or 'rfc3339'.")
+
+	serveCmd.Flags().StringVarP(&flagServeListen, "listen", "", ":8080", "Address to listen on.")
+	serveCmd.Flags().StringArrayVarP(&flagServeRepos, "repo", "", []string{}, "Repository to serve, as <name>=<path> to a clone or a mirror. Can be repeated.")
+	serveCmd.Flags().DurationVarP(&flagServeTimeout, "timeout", "", time.Minute, "Maximum duration of a request.")
+	serveCmd.Flags().StringArrayVarP(&flagTagsServers, "server", "s", []string{"https://registry-1.docker.io/"}, "Registry server to read existing tags from. Can be repeated.")
+	serveCmd.Flags().StringVarP(&flagTagsUsername, "user", "u", "", `Username for the registry. (env "REGISTRY_USERNAME")`)
+	serveCmd.Flags().StringVarP(&flagTagsPassword, "password", "p", "", `Password for the registry. Prefer --password-stdin. (env "REGISTRY_PASSWORD")`)
+	serveCmd.Flags().BoolVarP(&flagTagsPasswordStdin, "password-stdin", "", false, "Read the registry password from stdin.")
+	serveCmd.Flags().StringVarP(&flagTagsToken, "token", "t", "", `Bearer token for the registry. (env "REGISTRY_TOKEN")`)
+	serveCmd.Flags().BoolVarP(&flagTagsVerbose, "verbose", "", false, "Log registry requests to stderr.")
+	serveCmd.Flags().IntVarP(&flagTagsRetries, "retries", "", 5, "How many times to retry registry requests that fail with a transient error, a 429 or a 5xx status.")
+	serveCmd.Flags().IntVarP(&flagTagsPageSize, "page-size", "", 0, "How many tags to request per page when listing existing tags. By default, the registry decides.")
+	serveCmd.Flags().StringVarP(&flagTagsExistingPrefix, "existing-prefix", "", "", "Only consider existing tags that start with this prefix.")
+
 	rootCmd.AddCommand(
 		versionCmd,
 		tagsCmd,
+		chartCmd,
+		stampCmd,
+		ldflagsCmd,
+		checkCmd,
+		compareCmd,
+		listCmd,
+		lintTagsCmd,
+		historyCmd,
+		sourceDateEpochCmd,
+		serveCmd,
 	)
 
+	rootCmd.SetFlagErrorFunc(flagError)
+
 	if err := rootCmd.Execute(); err != nil {
-		os.Stderr.WriteString(err.Error())
-		os.Exit(1)
+		if !errors.Is(err, errFalse) {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+		os.Exit(exitCode(err))
 	}
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagServeListen  = ""
+	flagServeRepos   = []string{}
+	flagServeTimeout = time.Minute
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve <flags>",
+	Short: "Serves an HTTP API to compute versions of local repositories",
+	Long: `Serves an HTTP/JSON API that answers what sver would say for local clones or
+mirrors, set with --repo name=path:
+
+  GET /healthz
+  GET /v1/repos
+  GET /v1/repos/<name>/version
+  GET /v1/repos/<name>/next?type=minor
+  GET /v1/repos/<name>/tags?image=org/app
+  GET /v1/repos/<name>/list
+
+The tags endpoint reads existing tags from the registries of the config file,
+or from the --server registries, Docker Hub by default, with the registry flags
+of 'sver tags'. Keep the clones up to date, for example with
+'git fetch --tags' from a cron job.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(flagServeRepos) == 0 {
+			return errors.New("at least one --repo is required")
+		}
+
+		repos := map[string]string{}
+		for _, repo := range flagServeRepos {
+			parts := strings.SplitN(repo, "=", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return errors.Errorf("repository '%s' isn't in the name=path format", repo)
+			}
+
+			dir, err := filepath.Abs(parts[1])
+			if err != nil {
+				return err
+			}
+			repos[parts[0]] = dir
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		// The credentials are read once, since --password-stdin can't be read
+		// again for each request.
+		credentials, err := registryCredentials()
+		if err != nil {
+			return err
+		}
+
+		handler := sver.NewServer(sver.ServerOptions{
+			Repos: repos,
+			Destinations: func(image string) ([]sver.Destination, error) {
+				return tagDestinations(cmd, image, cfg, func() (sver.RegistryCredentials, error) {
+					return credentials, nil
+				})
+			},
+			TagOptions: func(image string) sver.TagOptions {
+				return sver.TagOptions{Policy: cfg.tagPolicy(image), Variants: cfg.Tags.Variants}
+			},
+			RegistryOptions: registryOptions(),
+			Timeout:         flagServeTimeout,
+		})
+
+		server := &http.Server{
+			Addr:              flagServeListen,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		errs := make(chan error, 1)
+		go func() {
+			fmt.Fprintf(os.Stderr, "listening on %s\n", flagServeListen)
+			errs <- server.ListenAndServe()
+		}()
+
+		select {
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		return server.Shutdown(shutdownCtx)
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
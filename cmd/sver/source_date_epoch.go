@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var flagSourceDateEpochOutput = "unix"
+
+var sourceDateEpochCmd = &cobra.Command{
+	Use:   "source-date-epoch <flags>",
+	Short: "Prints the date of the source, for reproducible builds",
+	Long: `Prints the date of the source as a Unix timestamp, the date that sver uses in
+development versions and in 'sver ldflags', so other build tools can share it:
+
+  export SOURCE_DATE_EPOCH=$(sver source-date-epoch)
+
+It's SOURCE_DATE_EPOCH if it's already set, or the committer date of HEAD, or
+its author date with --date author.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagSourceDateEpochOutput != "unix" && flagSourceDateEpochOutput != "rfc3339" {
+			return errors.Errorf("unknown output format '%s'; supported formats are 'unix' and 'rfc3339'", flagSourceDateEpochOutput)
+		}
+
+		date, err := sver.SourceDate(versionOptions(false))
+		if err != nil {
+			return err
+		}
+
+		if flagSourceDateEpochOutput == "rfc3339" {
+			fmt.Println(date.Format(time.RFC3339))
+		} else {
+			fmt.Println(date.Unix())
+		}
+
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var flagStampCheck = false
+
+var stampCmd = &cobra.Command{
+	Use:   "stamp <flags>",
+	Short: "Writes the current version into project files",
+	Long: `Writes the current version into the files listed in the 'stamp' section of
+the config file, keeping their formatting and comments.
+
+Each file sets one of:
+  json:  dot-separated path of a string value, like 'version'
+  yaml:  dot-separated path of a scalar value, like 'image.tag'
+  toml:  key prefixed with its table, like 'package.version'
+  regex: regular expression whose first capture group is the version
+
+With --check, no file is written, and the command fails if a file is out of
+date.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		if len(cfg.Stamp) == 0 {
+			return errors.New("no files to stamp; add them to the 'stamp' section of the config file")
+		}
+
+		version, err := currentVersion(false)
+		if err != nil {
+			return err
+		}
+
+		if flagPreRelease != "" {
+			version = sver.PreRelease(version, flagPreRelease)
+		}
+
+		outdated := []string{}
+		for _, target := range cfg.Stamp {
+			updater, err := target.Updater()
+			if err != nil {
+				return err
+			}
+
+			changed, err := sver.StampFile(target.File, updater, version, flagStampCheck)
+			if err != nil {
+				return err
+			}
+
+			if !changed {
+				continue
+			}
+
+			outdated = append(outdated, target.File)
+			if !flagStampCheck {
+				fmt.Printf("%s: %s\n", target.File, version)
+			}
+		}
+
+		if flagStampCheck && len(outdated) > 0 {
+			return errors.Errorf("not at version %s: %s", version, strings.Join(outdated, ", "))
+		}
+
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
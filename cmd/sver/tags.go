@@ -0,0 +1,421 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagTagsServers  = []string{}
+	flagTagsUsername = ""
+	flagTagsPassword = ""
+	flagTagsToken    = ""
+
+	flagTagsPasswordStdin = false
+
+	flagTagsSource = ""
+	flagTagsPush   = false
+	flagTagsDryRun = false
+
+	flagTagsPerRegistry = false
+
+	flagTagsChannels = false
+	flagTagsEdge     = false
+	flagTagsRules    = []string{}
+	flagTagsVariant  = ""
+
+	flagTagsVerbose        = false
+	flagTagsRetries        = 5
+	flagTagsPageSize       = 0
+	flagTagsExistingPrefix = ""
+
+	flagTagsExistingFile = ""
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags <flags> [image]",
+	Short: "Prints the tags that should be pushed to a docker registry",
+	Long: `Connects to a docker registry and lists all tags for an image.
+Depending on whether the current version is a development version and if
+it's the latest one, it returns the appropriate tags to be pushed.
+
+When publishing to several registries, the tags are calculated from the union
+of the tags that exist in all of them, or for each registry separately with
+--per-registry. Registries that disagree about which versions exist are
+reported on stderr.
+
+With --source and --push, the source image is copied or retagged to every
+computed tag directly in the registry.
+
+With --existing-tags-file, the existing tags are read from a file, or from
+stdin with '-', instead of from the registry. The image can then be omitted,
+unless tags are pushed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagPreRelease != "" && flagReleaseOnly {
+			return errors.New("Asked for a pre-release version, but the --release flag is on.")
+		}
+
+		if (flagTagsPush || flagTagsDryRun) && flagTagsSource == "" {
+			return errors.New("--push and --dry-run require a --source image")
+		}
+
+		if flagRef != "" && flagCheckGoMod {
+			return errors.New("--check-go-mod reads the go.mod files of the working tree, so it can't be used with --ref")
+		}
+
+		if flagTagsExistingFile == "-" && flagTagsPasswordStdin {
+			return errors.New("--existing-tags-file and --password-stdin can't both read from stdin")
+		}
+
+		version, err := currentVersion(flagReleaseOnly)
+		if err != nil {
+			return err
+		}
+
+		if flagPreRelease != "" {
+			version = sver.PreRelease(version, flagPreRelease)
+		}
+
+		if err := releaseChecks(version); err != nil {
+			return err
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		image := ""
+		if len(args) > 0 {
+			image = args[0]
+		}
+
+		opts, err := tagOptions(cfg, image)
+		if err != nil {
+			return err
+		}
+
+		if flagTagsExistingFile != "" {
+			return offlineTags(cmd, version, image, cfg, opts)
+		}
+
+		if image == "" {
+			return errors.New("an image is required, unless --existing-tags-file is set")
+		}
+
+		destinations, err := tagDestinations(cmd, image, cfg, registryCredentials)
+		if err != nil {
+			return err
+		}
+
+		sets, err := sver.ListDestinationTags(destinations, registryOptions()...)
+		if err != nil {
+			return err
+		}
+
+		printDrift(sver.TagDrift(sets))
+
+		if !flagTagsPerRegistry {
+			tags, err := calculateTags(version, sver.UnionTags(sets), opts, "")
+			if err != nil {
+				return err
+			}
+
+			for _, destination := range destinations {
+				if err := publishTags(destination, tags, len(destinations) > 1); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		for _, set := range sets {
+			tags, err := calculateTags(version, set.Tags, opts, set.Destination.Repository)
+			if err != nil {
+				return err
+			}
+
+			if err := publishTags(set.Destination, tags, true); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// offlineTags calculates tags from the existing tags in a file, without
+// listing them in a registry.
+func offlineTags(cmd *cobra.Command, version, image string, cfg *config, opts sver.TagOptions) error {
+	existingTags, err := readExistingTags(flagTagsExistingFile)
+	if err != nil {
+		return err
+	}
+
+	tags, err := calculateTags(version, existingTags, opts, "")
+	if err != nil {
+		return err
+	}
+
+	if !flagTagsPush && !flagTagsDryRun {
+		for _, tag := range tags {
+			fmt.Println(tag)
+		}
+
+		return nil
+	}
+
+	if image == "" {
+		return errors.New("an image is required to push tags")
+	}
+
+	destinations, err := tagDestinations(cmd, image, cfg, registryCredentials)
+	if err != nil {
+		return err
+	}
+
+	for _, destination := range destinations {
+		if err := pushTags(destination, tags); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readExistingTags(path string) ([]string, error) {
+	if path == "-" {
+		return sver.ReadTagList(os.Stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open existing tags file")
+	}
+	defer f.Close()
+
+	return sver.ReadTagList(f)
+}
+
+// tagOptions builds the tag options from flags. Tag rules set with --tag-rule
+// replace the policy from the config file.
+func tagOptions(cfg *config, image string) (sver.TagOptions, error) {
+	opts := sver.TagOptions{
+		Channels: flagTagsChannels,
+		Edge:     flagTagsEdge,
+		Policy:   cfg.tagPolicy(image),
+		Variant:  flagTagsVariant,
+		Variants: cfg.Tags.Variants,
+	}
+
+	if len(flagTagsRules) > 0 {
+		opts.Policy = sver.TagPolicy{}
+		for _, r := range flagTagsRules {
+			rule, err := sver.ParseTagRule(r)
+			if err != nil {
+				return opts, err
+			}
+
+			opts.Policy = append(opts.Policy, rule)
+		}
+	}
+
+	return opts, nil
+}
+
+// calculateTags calculates the tags of a version, and explains them with
+// --explain. The repository is empty when the tags are the same for all
+// registries.
+func calculateTags(version string, existingTags []string, opts sver.TagOptions, repository string) ([]string, error) {
+	d, err := sver.DeriveTags(version, existingTags, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if flagExplain {
+		explainTags(repository, d)
+	}
+
+	tags := d.Tags
+
+	if flagPrefix {
+		for i := range tags {
+			tags[i] = "v" + tags[i]
+		}
+	}
+
+	return tags, nil
+}
+
+// publishTags prints the tags for a destination, or pushes them if asked to.
+// With qualify, printed tags include the repository they're meant for.
+func publishTags(destination sver.Destination, tags []string, qualify bool) error {
+	if flagTagsPush || flagTagsDryRun {
+		return pushTags(destination, tags)
+	}
+
+	if qualify {
+		for _, tag := range tags {
+			fmt.Printf("%s:%s\n", destination.Repository, tag)
+		}
+
+		return nil
+	}
+
+	for _, tag := range tags {
+		fmt.Println(tag)
+	}
+
+	return nil
+}
+
+func pushTags(destination sver.Destination, tags []string) error {
+	opts := append(registryOptions(), sver.WithCredentials(destination.Credentials))
+	results, err := sver.PushTags(flagTagsSource, destination.Repository, tags, flagTagsDryRun, opts...)
+	for _, result := range results {
+		if result.Pushed {
+			fmt.Printf("pushed %s@%s\n", result.Reference, result.Digest)
+		} else {
+			fmt.Printf("would push %s@%s\n", result.Reference, result.Digest)
+		}
+	}
+
+	return err
+}
+
+func registryOptions() []sver.RegistryOption {
+	opts := []sver.RegistryOption{
+		sver.WithRetry(flagTagsRetries, time.Second),
+		sver.WithPageSize(flagTagsPageSize),
+		sver.WithTagPrefix(flagTagsExistingPrefix),
+	}
+
+	if flagTagsVerbose {
+		opts = append(opts, sver.WithLogger(func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		}))
+	}
+
+	return opts
+}
+
+func printDrift(drift []sver.VersionDrift) {
+	if len(drift) == 0 {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "registries disagree about which versions exist:")
+	for _, d := range drift {
+		fmt.Fprintf(os.Stderr, "  %s is missing from %s\n", d.Version, strings.Join(d.Missing, ", "))
+	}
+}
+
+// tagDestinations returns the registries from the config file, and the ones
+// set with --server. The default server is only used if the config file
+// doesn't list any registries. The credentials of the --server registries are
+// only read if these registries are used.
+func tagDestinations(cmd *cobra.Command, image string, cfg *config, credentials func() (sver.RegistryCredentials, error)) ([]sver.Destination, error) {
+	destinations := []sver.Destination{}
+	for _, registry := range cfg.Registries {
+		repository := registry.Repository
+		if repository == "" {
+			repository = image
+		}
+
+		host, err := serverHost(registry.Server)
+		if err != nil {
+			return nil, err
+		}
+
+		destinations = append(destinations, sver.Destination{
+			Repository: host + "/" + repository,
+			Credentials: sver.RegistryCredentials{
+				Username: registry.Username,
+				Password: os.Getenv(registry.PasswordEnv),
+				Token:    os.Getenv(registry.TokenEnv),
+			},
+		})
+	}
+
+	if len(destinations) > 0 && !cmd.Flags().Changed("server") {
+		return destinations, nil
+	}
+
+	serverCredentials, err := credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, server := range flagTagsServers {
+		host, err := serverHost(server)
+		if err != nil {
+			return nil, err
+		}
+
+		destinations = append(destinations, sver.Destination{
+			Repository:  host + "/" + image,
+			Credentials: serverCredentials,
+		})
+	}
+
+	return destinations, nil
+}
+
+func serverHost(server string) (string, error) {
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return "", err
+	}
+
+	if serverURL.Host != "" {
+		return serverURL.Host, nil
+	}
+
+	return server, nil
+}
+
+// registryCredentials builds the registry credentials from flags, falling back
+// to environment variables. Empty credentials make sver use the docker config.
+func registryCredentials() (sver.RegistryCredentials, error) {
+	credentials := sver.RegistryCredentials{
+		Username: flagOrEnv(flagTagsUsername, "REGISTRY_USERNAME"),
+		Token:    flagOrEnv(flagTagsToken, "REGISTRY_TOKEN"),
+	}
+
+	if flagTagsPasswordStdin {
+		if flagTagsPassword != "" {
+			return credentials, errors.New("--password and --password-stdin are mutually exclusive")
+		}
+
+		password, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return credentials, errors.Wrap(err, "failed to read password from stdin")
+		}
+
+		credentials.Password = strings.TrimRight(string(password), "\r\n")
+	} else {
+		credentials.Password = flagOrEnv(flagTagsPassword, "REGISTRY_PASSWORD")
+	}
+
+	return credentials, nil
+}
+
+func flagOrEnv(value, env string) string {
+	if value != "" {
+		return value
+	}
+
+	return os.Getenv(env)
+}
@@ -0,0 +1,570 @@
+package sver
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+)
+
+// APIChange is a change to the exported API of the Go packages of the
+// repository.
+type APIChange struct {
+	// Name is the changed identifier, qualified with the directory of its
+	// package, like `pkg/sver.Next` or `pkg/sver.TagOptions.Edge`.
+	Name       string
+	Compatible bool
+	// Description says what changed, like `added` or `removed`.
+	Description string
+}
+
+func (c APIChange) String() string {
+	return fmt.Sprintf("%s: %s", c.Name, c.Description)
+}
+
+// APIDiffOptions are the options of DiffAPI.
+type APIDiffOptions struct {
+	// From is the ref of the old API. Without it, the whole API of To is
+	// added.
+	From string
+	// To is the ref of the new API. It defaults to HEAD.
+	To string
+	// Dir is the directory of the repository. It defaults to the current
+	// directory.
+	Dir string
+	// Git runs the git commands. It defaults to ExecGit.
+	Git GitBackend
+}
+
+// DiffAPI compares the exported API of the Go packages at two git refs.
+// Removed identifiers and changed types are incompatible, added identifiers
+// are compatible. The packages are type-checked, so renaming parameters or
+// regrouping declarations isn't a change, but imported packages aren't loaded:
+// their types are only compared by name. Packages that can't be imported, like
+// `main`, `internal` and test packages, are skipped.
+func DiffAPI(opts APIDiffOptions) ([]APIChange, error) {
+	c := newAPIChecker(newRepo(opts.Dir, opts.Git))
+	if err := c.repo.verify(); err != nil {
+		return nil, errors.Wrap(err, "git error")
+	}
+
+	from := map[string]apiDecl{}
+	if opts.From != "" {
+		var err error
+		from, err = c.exportedAPI(opts.From)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	toRef := opts.To
+	if toRef == "" {
+		toRef = "HEAD"
+	}
+
+	to, err := c.exportedAPI(toRef)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []APIChange{}
+	for name, decl := range from {
+		newDecl, ok := to[name]
+		switch {
+		case !ok:
+			changes = append(changes, APIChange{Name: name, Description: "removed"})
+		case !c.identical(decl, newDecl):
+			changes = append(changes, APIChange{Name: name, Description: fmt.Sprintf("changed from '%s' to '%s'", decl.text, newDecl.text)})
+		}
+	}
+
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			changes = append(changes, APIChange{Name: name, Compatible: true, Description: "added"})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Compatible != changes[j].Compatible {
+			return !changes[i].Compatible
+		}
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes, nil
+}
+
+// SuggestNext compares the exported API at the last semver tag with the ref of
+// the options, and returns the next version type, 'major', 'minor' or 'patch',
+// with the changes that justify it. Incompatible changes before 1.0.0 suggest a
+// minor version.
+func SuggestNext(currentVersion string, opts Options) (string, []APIChange, error) {
+	r := opts.repo()
+	if err := r.verify(); err != nil {
+		return "", nil, errors.Wrap(err, "git error")
+	}
+
+	rev, err := r.resolve(opts.ref())
+	if err != nil {
+		return "", nil, err
+	}
+
+	tag, hasTag, err := r.lastTag(rev)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !hasTag {
+		tag = ""
+	}
+
+	changes, err := DiffAPI(APIDiffOptions{From: tag, To: rev, Dir: opts.Dir, Git: opts.Git})
+	if err != nil {
+		return "", nil, err
+	}
+
+	major, _, _, _, err := Parts(currentVersion)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to get version parts")
+	}
+
+	nextType := "patch"
+	for _, change := range changes {
+		if !change.Compatible {
+			if major == 0 {
+				return "minor", changes, nil
+			}
+			return "major", changes, nil
+		}
+		nextType = "minor"
+	}
+
+	return nextType, changes, nil
+}
+
+// apiDecl is an exported declaration: a package-level identifier, a method or
+// a struct field.
+type apiDecl struct {
+	// kind is what the declaration is, like `func` or `pointer method`. A
+	// declaration that changes kind is incompatible.
+	kind       string
+	typ        types.Type
+	typeParams *types.TypeParamList
+	// text is the declaration, as shown in changes.
+	text string
+}
+
+// apiChecker type-checks the packages of the refs compared by DiffAPI.
+// Imported packages are stubs that only declare the names used by the
+// repository, as types, and are shared by all refs, so their types are
+// identical at both refs.
+type apiChecker struct {
+	repo    repo
+	fset    *token.FileSet
+	imports map[string]*types.Package
+	// api is the package of the canonical types, and stubs are their named
+	// types, by qualified name.
+	api   *types.Package
+	stubs map[string]*types.Named
+}
+
+func newAPIChecker(r repo) *apiChecker {
+	return &apiChecker{
+		repo:    r,
+		fset:    token.NewFileSet(),
+		imports: map[string]*types.Package{},
+		api:     types.NewPackage("sver.api", "api"),
+		stubs:   map[string]*types.Named{},
+	}
+}
+
+// Import implements types.Importer.
+func (c *apiChecker) Import(importPath string) (*types.Package, error) {
+	return c.importedPackage(importPath), nil
+}
+
+func (c *apiChecker) importedPackage(importPath string) *types.Package {
+	pkg, ok := c.imports[importPath]
+	if !ok {
+		pkg = types.NewPackage(importPath, importName(importPath))
+		pkg.MarkComplete()
+		c.imports[importPath] = pkg
+	}
+
+	return pkg
+}
+
+// importName guesses the name of an imported package from its path, like
+// `yaml` for `gopkg.in/yaml.v3`.
+func importName(importPath string) string {
+	prefix, _, ok := module.SplitPathVersion(importPath)
+	if !ok {
+		prefix = importPath
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(path.Base(prefix), "go-"), "-go")
+	if i := strings.IndexAny(name, ".-"); i > 0 {
+		name = name[:i]
+	}
+
+	return name
+}
+
+// declareImported declares the names that a file uses from the packages it
+// imports.
+func (c *apiChecker) declareImported(file *ast.File) {
+	byName := map[string]*types.Package{}
+	for _, spec := range file.Imports {
+		importPath := strings.Trim(spec.Path.Value, "`\"")
+		pkg := c.importedPackage(importPath)
+
+		name := pkg.Name()
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		byName[name] = pkg
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		selector, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		ident, ok := selector.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		pkg, ok := byName[ident.Name]
+		if !ok || pkg.Scope().Lookup(selector.Sel.Name) != nil {
+			return true
+		}
+
+		obj := types.NewTypeName(token.NoPos, pkg, selector.Sel.Name, nil)
+		types.NewNamed(obj, c.marker(pkg.Path()+"."+obj.Name()), nil)
+		pkg.Scope().Insert(obj)
+
+		return true
+	})
+}
+
+// marker returns an interface with a single method named after a stub, so
+// interfaces that embed different stubs aren't identical.
+func (c *apiChecker) marker(name string) *types.Interface {
+	method := types.NewFunc(token.NoPos, c.api, name, types.NewSignatureType(nil, nil, nil, nil, nil, false))
+
+	return types.NewInterfaceType([]*types.Func{method}, nil).Complete()
+}
+
+// exportedAPI returns the exported declarations of the importable packages at
+// a ref, by qualified name.
+func (c *apiChecker) exportedAPI(ref string) (map[string]apiDecl, error) {
+	out, err := c.repo.git("ls-tree", "-r", "--name-only", "--full-tree", ref)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list files at '%s'", ref)
+	}
+
+	files := strings.Split(out, "\n")
+
+	nestedModules := []string{}
+	for _, file := range files {
+		if path.Base(file) == "go.mod" && path.Dir(file) != "." {
+			nestedModules = append(nestedModules, path.Dir(file)+"/")
+		}
+	}
+
+	// The files of each package, by directory and package name.
+	packages := map[string][]*ast.File{}
+	dirs := []string{}
+	for _, file := range files {
+		if !isAPIFile(file, nestedModules) {
+			continue
+		}
+
+		content, err := c.repo.git("show", ref+":"+file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read '%s' at '%s'", file, ref)
+		}
+
+		parsed, err := parser.ParseFile(c.fset, file, content, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse '%s' at '%s'", file, ref)
+		}
+
+		if parsed.Name.Name == "main" || strings.HasSuffix(parsed.Name.Name, "_test") {
+			continue
+		}
+
+		key := path.Dir(file) + ":" + parsed.Name.Name
+		if _, ok := packages[key]; !ok {
+			dirs = append(dirs, key)
+		}
+		packages[key] = append(packages[key], parsed)
+	}
+
+	api := map[string]apiDecl{}
+	for _, key := range dirs {
+		dir := key[:strings.LastIndex(key, ":")]
+		for _, file := range packages[key] {
+			c.declareImported(file)
+		}
+
+		// Errors, like the use of an imported value, leave invalid types, which
+		// are the same at both refs.
+		conf := types.Config{Importer: c, FakeImportC: true, Error: func(error) {}}
+		pkg, _ := conf.Check(dir, c.fset, packages[key], nil)
+
+		qualifier := ""
+		if dir != "." {
+			qualifier = dir + "."
+		}
+
+		addExportedDecls(api, qualifier, pkg)
+	}
+
+	return api, nil
+}
+
+func isAPIFile(file string, nestedModules []string) bool {
+	if !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
+		return false
+	}
+
+	for _, module := range nestedModules {
+		if strings.HasPrefix(file, module) {
+			return false
+		}
+	}
+
+	dir := path.Dir(file)
+	if dir == "." {
+		return true
+	}
+
+	for _, elem := range strings.Split(dir, "/") {
+		if elem == "internal" || elem == "testdata" || elem == "vendor" || strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func addExportedDecls(api map[string]apiDecl, qualifier string, pkg *types.Package) {
+	typeString := func(t types.Type) string {
+		return types.TypeString(t, func(other *types.Package) string {
+			if other == pkg {
+				return ""
+			}
+			return other.Name()
+		})
+	}
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+
+		switch o := obj.(type) {
+		case *types.Func:
+			sig, _ := o.Type().(*types.Signature)
+			api[qualifier+name] = apiDecl{kind: "func", typ: sig, typeParams: sig.TypeParams(), text: typeString(sig)}
+		case *types.Var:
+			api[qualifier+name] = apiDecl{kind: "var", typ: o.Type(), text: "var " + typeString(o.Type())}
+		case *types.Const:
+			api[qualifier+name] = apiDecl{kind: "const", typ: o.Type(), text: "const " + typeString(o.Type())}
+		case *types.TypeName:
+			addExportedType(api, qualifier, o, typeString)
+		}
+	}
+}
+
+func addExportedType(api map[string]apiDecl, qualifier string, obj *types.TypeName, typeString func(types.Type) string) {
+	name := qualifier + obj.Name()
+
+	named, ok := obj.Type().(*types.Named)
+	if obj.IsAlias() || !ok {
+		api[name] = apiDecl{kind: "alias", typ: obj.Type(), text: fmt.Sprintf("type %s = %s", obj.Name(), typeString(obj.Type()))}
+		return
+	}
+
+	typeParams := ""
+	if named.TypeParams().Len() > 0 {
+		params := []string{}
+		for i := 0; i < named.TypeParams().Len(); i++ {
+			param := named.TypeParams().At(i)
+			params = append(params, param.Obj().Name()+" "+typeString(param.Constraint()))
+		}
+		typeParams = "[" + strings.Join(params, ", ") + "]"
+	}
+
+	for i := 0; i < named.NumMethods(); i++ {
+		method := named.Method(i)
+		if !method.Exported() {
+			continue
+		}
+
+		sig, _ := method.Type().(*types.Signature)
+		kind := "method"
+		if _, ok := sig.Recv().Type().(*types.Pointer); ok {
+			kind = "pointer method"
+		}
+
+		api[name+"."+method.Name()] = apiDecl{
+			kind: kind,
+			typ:  sig,
+			text: fmt.Sprintf("func (%s) %s%s", typeString(sig.Recv().Type()), method.Name(), strings.TrimPrefix(typeString(sig), "func")),
+		}
+	}
+
+	structType, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		api[name] = apiDecl{
+			kind:       "type",
+			typ:        named.Underlying(),
+			typeParams: named.TypeParams(),
+			text:       fmt.Sprintf("type %s%s %s", obj.Name(), typeParams, typeString(named.Underlying())),
+		}
+		return
+	}
+
+	// Fields of structs are compared one by one, so adding a field is a
+	// compatible change.
+	api[name] = apiDecl{kind: "struct", typeParams: named.TypeParams(), text: fmt.Sprintf("type %s%s struct", obj.Name(), typeParams)}
+	for i := 0; i < structType.NumFields(); i++ {
+		field := structType.Field(i)
+		if !field.Exported() {
+			continue
+		}
+
+		if field.Embedded() {
+			api[name+"."+field.Name()] = apiDecl{kind: "embedded", typ: field.Type(), text: "embedded " + typeString(field.Type())}
+			continue
+		}
+
+		api[name+"."+field.Name()] = apiDecl{kind: "field", typ: field.Type(), text: typeString(field.Type())}
+	}
+}
+
+// identical tells if two declarations of the same identifier at different refs
+// are compatible.
+func (c *apiChecker) identical(a, b apiDecl) bool {
+	if a.kind != b.kind || a.typeParams.Len() != b.typeParams.Len() {
+		return false
+	}
+
+	for i := 0; i < a.typeParams.Len(); i++ {
+		if !types.Identical(c.canonical(a.typeParams.At(i).Constraint()), c.canonical(b.typeParams.At(i).Constraint())) {
+			return false
+		}
+	}
+
+	if a.typ == nil || b.typ == nil {
+		return a.typ == nil && b.typ == nil
+	}
+
+	return types.Identical(c.canonical(a.typ), c.canonical(b.typ))
+}
+
+// canonical returns a type that's identical to the same type checked at
+// another ref. Named types and type parameters, which are declared once per
+// ref, are replaced with stubs shared by all refs, and so are the receivers
+// and type parameters of signatures, which are compared separately.
+func (c *apiChecker) canonical(t types.Type) types.Type {
+	switch t := t.(type) {
+	case *types.Named:
+		if t.Obj().Pkg() == nil {
+			// Predeclared, like `error` and `comparable`.
+			return t
+		}
+
+		name := t.Obj().Pkg().Path() + "." + t.Obj().Name()
+		if t.TypeArgs().Len() > 0 {
+			args := []string{}
+			for i := 0; i < t.TypeArgs().Len(); i++ {
+				args = append(args, types.TypeString(c.canonical(t.TypeArgs().At(i)), nil))
+			}
+			name += "[" + strings.Join(args, ", ") + "]"
+		}
+
+		return c.stub(name)
+	case *types.TypeParam:
+		return c.stub(fmt.Sprintf("type parameter %d", t.Index()))
+	case *types.Pointer:
+		return types.NewPointer(c.canonical(t.Elem()))
+	case *types.Slice:
+		return types.NewSlice(c.canonical(t.Elem()))
+	case *types.Array:
+		return types.NewArray(c.canonical(t.Elem()), t.Len())
+	case *types.Map:
+		return types.NewMap(c.canonical(t.Key()), c.canonical(t.Elem()))
+	case *types.Chan:
+		return types.NewChan(t.Dir(), c.canonical(t.Elem()))
+	case *types.Signature:
+		return types.NewSignatureType(nil, nil, nil, c.canonicalTuple(t.Params()), c.canonicalTuple(t.Results()), t.Variadic())
+	case *types.Struct:
+		fields := []*types.Var{}
+		tags := []string{}
+		for i := 0; i < t.NumFields(); i++ {
+			field := t.Field(i)
+			fields = append(fields, types.NewField(token.NoPos, c.api, field.Name(), c.canonical(field.Type()), field.Embedded()))
+			tags = append(tags, t.Tag(i))
+		}
+		return types.NewStruct(fields, tags)
+	case *types.Interface:
+		// The methods of embedded interfaces, and the markers of stubs, are in
+		// the method set.
+		methods := []*types.Func{}
+		for i := 0; i < t.NumMethods(); i++ {
+			method := t.Method(i)
+			sig, _ := c.canonical(method.Type()).(*types.Signature)
+			methods = append(methods, types.NewFunc(token.NoPos, c.api, method.Name(), sig))
+		}
+
+		embeddeds := []types.Type{}
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			if _, ok := t.EmbeddedType(i).Underlying().(*types.Interface); ok && t.EmbeddedType(i) != types.Universe.Lookup("comparable").Type() {
+				continue
+			}
+			embeddeds = append(embeddeds, c.canonical(t.EmbeddedType(i)))
+		}
+		return types.NewInterfaceType(methods, embeddeds).Complete()
+	case *types.Union:
+		terms := []*types.Term{}
+		for i := 0; i < t.Len(); i++ {
+			terms = append(terms, types.NewTerm(t.Term(i).Tilde(), c.canonical(t.Term(i).Type())))
+		}
+		return types.NewUnion(terms)
+	}
+
+	return t
+}
+
+func (c *apiChecker) canonicalTuple(tuple *types.Tuple) *types.Tuple {
+	vars := []*types.Var{}
+	for i := 0; i < tuple.Len(); i++ {
+		vars = append(vars, types.NewParam(token.NoPos, c.api, "", c.canonical(tuple.At(i).Type())))
+	}
+
+	return types.NewTuple(vars...)
+}
+
+func (c *apiChecker) stub(name string) *types.Named {
+	named, ok := c.stubs[name]
+	if !ok {
+		named = types.NewNamed(types.NewTypeName(token.NoPos, c.api, name, nil), c.marker(name), nil)
+		c.stubs[name] = named
+	}
+
+	return named
+}
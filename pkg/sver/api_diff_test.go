@@ -0,0 +1,214 @@
+package sver_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const apiBase = `package lib
+
+// Version is the version.
+const Version = "1"
+
+type Options struct {
+	Name string
+	hidden bool
+}
+
+type Source interface {
+	Read() ([]byte, error)
+}
+
+type Set[T comparable] map[T]struct{}
+
+func (s Set[T]) Has(v T) bool { _, ok := s[v]; return ok }
+
+func Open(name string) (*Options, error) { return nil, nil }
+
+func helper() {}
+`
+
+var _ = Describe("api diff", func() {
+	var dir string
+
+	writeFile := func(name, content string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)).To(Succeed())
+	}
+
+	commit := func() {
+		_, err := git("add", "-A")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = git("commit", "--no-gpg-sign", "--message", "change")
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "sver-api")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Chdir(dir)).To(Succeed())
+
+		_, err = git("init")
+		Expect(err).ToNot(HaveOccurred())
+		writeFile("lib.go", apiBase)
+		writeFile("cmd/tool/main.go", "package main\n\nfunc Run() {}\n")
+		writeFile("internal/impl/impl.go", "package impl\n\nfunc Do() {}\n")
+		commit()
+
+		_, err = git("tag", "v1.2.0")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("suggests a patch version without API changes", func() {
+		writeFile("lib.go", apiBase+"\nfunc helper2() {}\n")
+		writeFile("cmd/tool/main.go", "package main\n\nfunc Run(x int) {}\n")
+		writeFile("internal/impl/impl.go", "package impl\n\nfunc Do(x int) {}\n")
+		commit()
+
+		nextType, changes, err := sver.SuggestNext("1.2.0", sver.Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(BeEmpty())
+		Expect(nextType).To(Equal("patch"))
+	})
+
+	It("suggests a patch version for renamed parameters", func() {
+		renamed := strings.Replace(apiBase, "func Open(name string)", "func Open(path string)", 1)
+		renamed = strings.Replace(renamed, "func (s Set[T]) Has(v T) bool { _, ok := s[v]; return ok }",
+			"func (set Set[K]) Has(value K) bool { _, ok := set[value]; return ok }", 1)
+		writeFile("lib.go", renamed+"\nfunc Join(a string, b string) string { return a + b }\n")
+		commit()
+		_, err := git("tag", "v1.3.0")
+		Expect(err).ToNot(HaveOccurred())
+
+		writeFile("lib.go", renamed+"\nfunc Join(first, second string) (joined string) { return first + second }\n")
+		commit()
+
+		nextType, changes, err := sver.SuggestNext("1.3.0", sver.Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(BeEmpty())
+		Expect(nextType).To(Equal("patch"))
+	})
+
+	It("compares imported types by name", func() {
+		copyFile := "package lib\n\nimport (\n\t\"io\"\n\tyaml \"gopkg.in/yaml.v3\"\n)\n\n"
+		writeFile("copy.go", copyFile+"func Copy(w io.Writer, n *yaml.Node) error { return nil }\n")
+		commit()
+		_, err := git("tag", "v1.3.0")
+		Expect(err).ToNot(HaveOccurred())
+
+		writeFile("copy.go", copyFile+"func Copy(out io.Writer, node *yaml.Node) (err error) { return nil }\n")
+		commit()
+
+		changes, err := sver.DiffAPI(sver.APIDiffOptions{From: "v1.3.0"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(BeEmpty())
+
+		writeFile("copy.go", copyFile+"func Copy(r io.Reader, n *yaml.Node) error { return nil }\n")
+		commit()
+
+		changes, err = sver.DiffAPI(sver.APIDiffOptions{From: "v1.3.0"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(Equal([]sver.APIChange{{
+			Name:        "Copy",
+			Description: "changed from 'func(w io.Writer, n *yaml.Node) error' to 'func(r io.Reader, n *yaml.Node) error'",
+		}}))
+	})
+
+	It("suggests a minor version for added identifiers", func() {
+		writeFile("lib.go", apiBase+"\nfunc (o *Options) Close() error { return nil }\n")
+		writeFile("sub/sub.go", "package sub\n\nvar Default int\n")
+		commit()
+
+		nextType, changes, err := sver.SuggestNext("1.2.0", sver.Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nextType).To(Equal("minor"))
+		Expect(changes).To(Equal([]sver.APIChange{
+			{Name: "Options.Close", Compatible: true, Description: "added"},
+			{Name: "sub.Default", Compatible: true, Description: "added"},
+		}))
+	})
+
+	It("treats new struct fields as compatible", func() {
+		writeFile("lib.go", apiBase+"\ntype More struct{ Options }\n")
+		commit()
+
+		changes, err := sver.DiffAPI(sver.APIDiffOptions{From: "v1.2.0"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes).To(ConsistOf(
+			sver.APIChange{Name: "More", Compatible: true, Description: "added"},
+			sver.APIChange{Name: "More.Options", Compatible: true, Description: "added"},
+		))
+	})
+
+	It("suggests a major version for removed and changed identifiers", func() {
+		changed := `package lib
+
+const Version = "2"
+
+type Options struct {
+	Name  string
+	Debug bool
+}
+
+type Source interface {
+	Read() ([]byte, error)
+	Close() error
+}
+
+type Set[T comparable] map[T]struct{}
+
+func Open(name string, mode int) (*Options, error) { return nil, nil }
+`
+		writeFile("lib.go", changed)
+		commit()
+
+		nextType, changes, err := sver.SuggestNext("1.2.0", sver.Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nextType).To(Equal("major"))
+		Expect(changes).To(HaveLen(4))
+		Expect(changes[0]).To(Equal(sver.APIChange{
+			Name:        "Open",
+			Description: "changed from 'func(name string) (*Options, error)' to 'func(name string, mode int) (*Options, error)'",
+		}))
+		Expect(changes[1]).To(Equal(sver.APIChange{Name: "Set.Has", Description: "removed"}))
+		Expect(changes[2].Name).To(Equal("Source"))
+		Expect(changes[2].Compatible).To(BeFalse())
+		Expect(changes[3]).To(Equal(sver.APIChange{Name: "Options.Debug", Compatible: true, Description: "added"}))
+	})
+
+	It("suggests a version at the ref of another repository", func() {
+		repo := svertest.NewRepo(GinkgoT()).
+			CommitFile("lib.go", apiBase, "initial").Tag("v1.2.0").
+			CommitFile("lib.go", apiBase+"\nfunc Close() error { return nil }\n", "add Close").
+			CommitFile("lib.go", "package lib\n", "remove everything")
+		defer repo.Close()
+
+		opts := repo.Options()
+		opts.Ref = "HEAD~1"
+		nextType, changes, err := sver.SuggestNext("1.2.0", opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nextType).To(Equal("minor"))
+		Expect(changes).To(Equal([]sver.APIChange{{Name: "Close", Compatible: true, Description: "added"}}))
+	})
+
+	It("suggests a minor version for incompatible changes before 1.0.0", func() {
+		writeFile("lib.go", "package lib\n")
+		commit()
+
+		nextType, _, err := sver.SuggestNext("0.4.0", sver.Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nextType).To(Equal("minor"))
+	})
+})
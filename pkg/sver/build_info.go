@@ -0,0 +1,125 @@
+package sver
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BuildInfo is the version data that binaries embed with `-X` linker flags,
+// like the `ver`, `commit` and `date` variables of pkg/version.
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// LinkerVars are the fully qualified names of the variables that receive the
+// build info, like `main.version`. Empty names are skipped.
+type LinkerVars struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// CurrentBuildInfo returns the build info of HEAD for a version. The date is
+// the commit date in UTC, so builds of the same commit are reproducible.
+func CurrentBuildInfo(version string) (BuildInfo, error) {
+	return DeriveBuildInfo(version, Options{})
+}
+
+// DeriveBuildInfo returns the build info of the ref of opts for a version. The
+// date is the SourceDate, the same as in development versions.
+func DeriveBuildInfo(version string, opts Options) (BuildInfo, error) {
+	r := opts.repo()
+	if err := r.verify(); err != nil {
+		return BuildInfo{}, err
+	}
+
+	rev, err := r.resolve(opts.ref())
+	if err != nil {
+		return BuildInfo{}, err
+	}
+
+	commit, err := r.git("rev-parse", rev)
+	if err != nil {
+		return BuildInfo{}, err
+	}
+
+	date, _, err := r.sourceDate(rev, opts)
+	if err != nil {
+		return BuildInfo{}, err
+	}
+
+	return BuildInfo{
+		Version: version,
+		Commit:  commit,
+		Date:    date.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// LDFlags returns the value of the `-ldflags` argument of `go build` that sets
+// the variables.
+func (i BuildInfo) LDFlags(vars LinkerVars) string {
+	flags := []string{}
+	for _, v := range i.assignments(vars) {
+		flags = append(flags, fmt.Sprintf("-X %s=%s", v[0], v[1]))
+	}
+
+	return strings.Join(flags, " ")
+}
+
+// GoSource returns a Go file of package pkg that sets the variables in an init
+// function, for use with `go generate` instead of linker flags. Variables can
+// be qualified with their package, like for LDFlags, or not. If pkg is empty,
+// it's taken from the qualified variables.
+func (i BuildInfo) GoSource(pkg string, vars LinkerVars) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	for _, v := range i.assignments(vars) {
+		name := v[0]
+		if dot := strings.LastIndex(name, "."); dot != -1 {
+			varPkg := name[:dot]
+			varPkg = varPkg[strings.LastIndex(varPkg, "/")+1:]
+			if pkg != "" && varPkg != pkg {
+				return nil, errors.Errorf("variable '%s' isn't in package '%s'", name, pkg)
+			}
+
+			pkg = varPkg
+			name = name[dot+1:]
+		}
+
+		fmt.Fprintf(buf, "\t%s = %q\n", name, v[1])
+	}
+
+	if buf.Len() == 0 {
+		return nil, errors.New("no variables to set")
+	}
+
+	if pkg == "" {
+		return nil, errors.New("unknown package; qualify the variables with their package, like 'main.version'")
+	}
+
+	src := fmt.Sprintf("// Code generated by sver ldflags; DO NOT EDIT.\n\npackage %s\n\nfunc init() {\n%s}\n", pkg, buf.String())
+
+	return format.Source([]byte(src))
+}
+
+func (i BuildInfo) assignments(vars LinkerVars) [][2]string {
+	result := [][2]string{}
+	for _, v := range [][2]string{
+		{vars.Version, i.Version},
+		{vars.Commit, i.Commit},
+		{vars.Date, i.Date},
+	} {
+		if v[0] != "" {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,100 @@
+package sver_test
+
+import (
+	"os"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("build info", func() {
+	info := sver.BuildInfo{
+		Version: "1.2.3",
+		Commit:  "0123456789abcdef0123456789abcdef01234567",
+		Date:    "2022-10-01T12:00:00Z",
+	}
+
+	Context("linker flags", func() {
+		It("sets all variables", func() {
+			flags := info.LDFlags(sver.LinkerVars{Version: "main.version", Commit: "main.commit", Date: "main.date"})
+			Expect(flags).To(Equal("-X main.version=1.2.3 -X main.commit=0123456789abcdef0123456789abcdef01234567 -X main.date=2022-10-01T12:00:00Z"))
+		})
+
+		It("skips unset variables", func() {
+			flags := info.LDFlags(sver.LinkerVars{Version: "github.com/org/app/pkg/version.ver"})
+			Expect(flags).To(Equal("-X github.com/org/app/pkg/version.ver=1.2.3"))
+		})
+	})
+
+	Context("generated source", func() {
+		It("sets the variables in an init function", func() {
+			src, err := info.GoSource("", sver.LinkerVars{Version: "github.com/org/app/pkg/version.ver", Date: "github.com/org/app/pkg/version.date"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(src)).To(Equal(`// Code generated by sver ldflags; DO NOT EDIT.
+
+package version
+
+func init() {
+	ver = "1.2.3"
+	date = "2022-10-01T12:00:00Z"
+}
+`))
+		})
+
+		It("accepts unqualified variables with a package", func() {
+			src, err := info.GoSource("main", sver.LinkerVars{Version: "version", Commit: "main.commit"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(src)).To(ContainSubstring("package main\n"))
+			Expect(string(src)).To(ContainSubstring("\tversion = \"1.2.3\"\n"))
+			Expect(string(src)).To(ContainSubstring("\tcommit = \"0123456789abcdef0123456789abcdef01234567\"\n"))
+		})
+
+		It("fails for variables in different packages", func() {
+			_, err := info.GoSource("", sver.LinkerVars{Version: "main.version", Commit: "other.commit"})
+			Expect(err).To(MatchError(ContainSubstring("isn't in package")))
+		})
+
+		It("fails for unqualified variables without a package", func() {
+			_, err := info.GoSource("", sver.LinkerVars{Version: "version"})
+			Expect(err).To(MatchError(ContainSubstring("unknown package")))
+		})
+	})
+
+	Context("from git", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = os.MkdirTemp("", "sver")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.Chdir(dir)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("uses the full commit hash and the commit date in UTC", func() {
+			createGitDirWithTag("v1.2.3")
+
+			os.Setenv("GIT_COMMITTER_DATE", "2022-10-01T14:00:00+02:00")
+			_, err := git("commit", "--amend", "--no-edit")
+			os.Unsetenv("GIT_COMMITTER_DATE")
+			Expect(err).ToNot(HaveOccurred())
+
+			commit, err := git("rev-parse", "HEAD")
+			Expect(err).ToNot(HaveOccurred())
+
+			info, err := sver.CurrentBuildInfo("1.2.3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info).To(Equal(sver.BuildInfo{
+				Version: "1.2.3",
+				Commit:  commit,
+				Date:    "2022-10-01T12:00:00Z",
+			}))
+			Expect(info.Commit).To(HaveLen(40))
+		})
+	})
+})
@@ -0,0 +1,241 @@
+package sver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// chartIndexTimeout is how long downloading a chart repository index may take.
+const chartIndexTimeout = time.Minute
+
+// ChartIndexVersions returns the versions of a chart in a chart repository
+// index, read from a local index.yaml file or from an HTTP(S) URL. The context
+// cancels the download.
+func ChartIndexVersions(ctx context.Context, index, chartName string) ([]string, error) {
+	content, err := readChartIndex(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := struct {
+		Entries map[string][]struct {
+			Version string `yaml:"version"`
+		} `yaml:"entries"`
+	}{}
+	if err := yaml.Unmarshal(content, &parsed); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse chart index [%s]", index)
+	}
+
+	versions := []string{}
+	for _, entry := range parsed.Entries[chartName] {
+		versions = append(versions, entry.Version)
+	}
+
+	return versions, nil
+}
+
+func readChartIndex(ctx context.Context, index string) ([]byte, error) {
+	if !strings.HasPrefix(index, "http://") && !strings.HasPrefix(index, "https://") {
+		content, err := os.ReadFile(index)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read chart index [%s]", index)
+		}
+
+		return content, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, index, http.NoBody)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid chart index URL [%s]", index)
+	}
+
+	client := &http.Client{Timeout: chartIndexTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download chart index [%s]", index)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to download chart index [%s]: %s", index, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// OCIChartVersions returns the versions of a chart in an OCI repository.
+// Helm replaces the `+` of versions with `_` in tags, which is reverted here.
+func OCIChartVersions(repoName string, opts ...RegistryOption) ([]string, error) {
+	tags, err := ListImageTags(repoName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, tag := range tags {
+		tags[i] = strings.ReplaceAll(tag, "_", "+")
+	}
+
+	return tags, nil
+}
+
+// VersionExists returns true if one of the existing versions is the same
+// semantic version as version, ignoring a "v" prefix and build metadata.
+// Existing versions that aren't semantic versions are skipped.
+func VersionExists(version string, existing []string) (bool, error) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to parse version [%s]", version)
+	}
+
+	for _, e := range existing {
+		ev, err := semver.NewVersion(e)
+		if err != nil {
+			continue
+		}
+		if ev.Equal(v) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// IsLatestVersion returns true if version would get the `latest` tag, given
+// the existing versions. It uses the same ordering as CalculateTagsForVersion.
+func IsLatestVersion(version string, existing []string) (bool, error) {
+	tags, err := CalculateTagsForVersion(version, existing)
+	if err != nil {
+		return false, err
+	}
+
+	for _, tag := range tags {
+		if tag == "latest" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// UpdateChart sets the version of a Chart.yaml file and, if appVersion isn't
+// empty, its appVersion. The file is edited in place, so its formatting,
+// comments and quoting are preserved.
+func UpdateChart(path, version, appVersion string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read chart [%s]", path)
+	}
+
+	content, err = setYAMLValue(content, []string{"version"}, version, true)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update chart [%s]", path)
+	}
+
+	if appVersion != "" {
+		content, err = setYAMLValue(content, []string{"appVersion"}, appVersion, true)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update chart [%s]", path)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, info.Mode())
+}
+
+// setYAMLValue replaces the scalar value at a path of mapping keys, keeping the
+// rest of the document as is. With appendMissing, a missing top-level key is
+// appended.
+func setYAMLValue(content []byte, path []string, value string, appendMissing bool) ([]byte, error) {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("not a YAML mapping")
+	}
+
+	node := doc.Content[0]
+	for depth, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil, errors.Errorf("'%s' isn't a mapping", strings.Join(path[:depth], "."))
+		}
+
+		var child *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				child = node.Content[i+1]
+				break
+			}
+		}
+
+		if child == nil {
+			if !appendMissing || len(path) > 1 {
+				return nil, errors.Errorf("'%s' not found", strings.Join(path[:depth+1], "."))
+			}
+
+			if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+				content = append(content, '\n')
+			}
+
+			return append(content, []byte(key+": "+value+"\n")...), nil
+		}
+
+		node = child
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		return nil, errors.Errorf("'%s' isn't a scalar value", strings.Join(path, "."))
+	}
+
+	return replaceScalar(content, node, value)
+}
+
+// replaceScalar replaces the text of a single-line scalar node in content,
+// keeping its quoting style.
+func replaceScalar(content []byte, node *yaml.Node, value string) ([]byte, error) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if node.Line < 1 || node.Line > len(lines) {
+		return nil, errors.Errorf("value of line %d not found", node.Line)
+	}
+
+	line := lines[node.Line-1]
+	start := node.Column - 1
+	end := start
+
+	switch node.Style {
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		quote := line[start]
+		closing := bytes.IndexByte(line[start+1:], quote)
+		if closing == -1 {
+			return nil, errors.Errorf("multi-line values aren't supported (line %d)", node.Line)
+		}
+		end = start + closing + 2
+		value = string(quote) + value + string(quote)
+	case yaml.FlowStyle, yaml.TaggedStyle, yaml.LiteralStyle, yaml.FoldedStyle:
+		return nil, errors.Errorf("unsupported value style on line %d", node.Line)
+	default:
+		end = start + len(node.Value)
+		if end > len(line) || string(line[start:end]) != node.Value {
+			return nil, errors.Errorf("multi-line values aren't supported (line %d)", node.Line)
+		}
+	}
+
+	updated := append(append(append([]byte{}, line[:start]...), value...), line[end:]...)
+	lines[node.Line-1] = updated
+
+	return bytes.Join(lines, nil), nil
+}
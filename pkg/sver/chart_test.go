@@ -0,0 +1,171 @@
+package sver_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const chartIndex = `apiVersion: v1
+entries:
+  sver:
+    - name: sver
+      version: 1.2.0
+    - name: sver
+      version: 1.1.0
+  other:
+    - name: other
+      version: 3.0.0
+`
+
+var _ = Describe("chart", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "sver-chart")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Context("reading versions from a chart index", func() {
+		It("reads a local index file", func() {
+			index := filepath.Join(dir, "index.yaml")
+			Expect(os.WriteFile(index, []byte(chartIndex), 0600)).To(Succeed())
+
+			versions, err := sver.ChartIndexVersions(context.Background(), index, "sver")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(versions).To(Equal([]string{"1.2.0", "1.1.0"}))
+		})
+
+		It("downloads an index over HTTP", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, chartIndex)
+			}))
+			defer server.Close()
+
+			versions, err := sver.ChartIndexVersions(context.Background(), server.URL+"/index.yaml", "other")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(versions).To(Equal([]string{"3.0.0"}))
+		})
+
+		It("returns no versions for an unknown chart", func() {
+			index := filepath.Join(dir, "index.yaml")
+			Expect(os.WriteFile(index, []byte(chartIndex), 0600)).To(Succeed())
+
+			versions, err := sver.ChartIndexVersions(context.Background(), index, "unknown")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(versions).To(BeEmpty())
+		})
+	})
+
+	Context("reading versions from an OCI repository", func() {
+		It("translates tags back to versions", func() {
+			registry := svertest.NewRegistry(GinkgoT())
+			defer registry.Close()
+			registry.PushImage("charts/sver", "1.0.0", "1.1.0_build.1")
+
+			versions, err := sver.OCIChartVersions(registry.Repository("charts/sver"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(versions).To(ConsistOf("1.0.0", "1.1.0+build.1"))
+		})
+	})
+
+	Context("deciding if a version exists", func() {
+		It("compares semantic versions", func() {
+			exists, err := sver.VersionExists("1.2.0", []string{"1.1.0", "v1.2.0"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("ignores build metadata and invalid versions", func() {
+			exists, err := sver.VersionExists("1.2.0", []string{"not-a-version", "1.2.0+build.5"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("doesn't match other versions", func() {
+			exists, err := sver.VersionExists("1.2.0", []string{"1.2.0-rc.1", "1.2.1"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Context("deciding if a version is the latest", func() {
+		It("is the latest if no existing release is newer", func() {
+			latest, err := sver.IsLatestVersion("1.3.0", []string{"1.2.0", "1.1.0"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(latest).To(BeTrue())
+		})
+
+		It("isn't the latest if a newer release exists", func() {
+			latest, err := sver.IsLatestVersion("1.1.1", []string{"1.2.0", "1.1.0"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(latest).To(BeFalse())
+		})
+	})
+
+	Context("updating Chart.yaml", func() {
+		chart := `# The sver chart
+apiVersion: v2
+name: sver
+version: 0.1.0 # bumped by CI
+appVersion: "0.1.0"
+dependencies:
+  - name: other
+    version: 3.0.0
+`
+
+		var path string
+
+		BeforeEach(func() {
+			path = filepath.Join(dir, "Chart.yaml")
+			Expect(os.WriteFile(path, []byte(chart), 0600)).To(Succeed())
+		})
+
+		It("sets version and appVersion, keeping comments and quotes", func() {
+			Expect(sver.UpdateChart(path, "1.2.3", "1.2.3")).To(Succeed())
+
+			content, err := os.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal(`# The sver chart
+apiVersion: v2
+name: sver
+version: 1.2.3 # bumped by CI
+appVersion: "1.2.3"
+dependencies:
+  - name: other
+    version: 3.0.0
+`))
+		})
+
+		It("leaves appVersion alone if it's empty", func() {
+			Expect(sver.UpdateChart(path, "1.2.3", "")).To(Succeed())
+
+			content, err := os.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(`appVersion: "0.1.0"`))
+		})
+
+		It("adds appVersion if it's missing", func() {
+			Expect(os.WriteFile(path, []byte("apiVersion: v2\nname: sver\nversion: 0.1.0"), 0600)).To(Succeed())
+			Expect(sver.UpdateChart(path, "1.2.3", "1.2.3")).To(Succeed())
+
+			content, err := os.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal("apiVersion: v2\nname: sver\nversion: 1.2.3\nappVersion: 1.2.3\n"))
+		})
+	})
+})
@@ -0,0 +1,72 @@
+package sver
+
+import (
+	"github.com/Masterminds/semver"
+	semverv3 "github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+)
+
+// Constraint is a set of version ranges, like `>=1.4, <2` or `^1.4 || ^2`, in
+// the syntax of github.com/Masterminds/semver. It's checked with v3 of the
+// library, since v1 mishandles partial versions, like `<2`.
+//
+// A pre-release version only matches a comparator that has a pre-release, so
+// `>=1.4` doesn't match `2.0.0-rc.1`.
+type Constraint struct {
+	raw         string
+	constraints *semverv3.Constraints
+}
+
+// ParseConstraint parses a constraint like `>=1.4, <2`.
+func ParseConstraint(constraint string) (*Constraint, error) {
+	constraints, err := semverv3.NewConstraint(constraint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid constraint '%s'", constraint)
+	}
+
+	return &Constraint{raw: constraint, constraints: constraints}, nil
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// Check returns true if version matches the constraint. With
+// includePreReleases, a pre-release version also matches if its release does.
+func (c *Constraint) Check(version string, includePreReleases bool) (bool, error) {
+	v, err := semverv3.NewVersion(version)
+	if err != nil {
+		return false, errors.WithStack(&ErrNotSemver{Tag: version})
+	}
+
+	if c.constraints.Check(v) {
+		return true, nil
+	}
+
+	if !includePreReleases || v.Prerelease() == "" {
+		return false, nil
+	}
+
+	release, err := v.SetPrerelease("")
+	if err != nil {
+		return false, err
+	}
+
+	return c.constraints.Check(&release), nil
+}
+
+// CompareVersions returns -1, 0 or 1 if a is lower than, equal to or greater
+// than b, by semver precedence.
+func CompareVersions(a, b string) (int, error) {
+	va, err := semver.NewVersion(a)
+	if err != nil {
+		return 0, errors.WithStack(&ErrNotSemver{Tag: a})
+	}
+
+	vb, err := semver.NewVersion(b)
+	if err != nil {
+		return 0, errors.WithStack(&ErrNotSemver{Tag: b})
+	}
+
+	return va.Compare(vb), nil
+}
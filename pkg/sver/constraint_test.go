@@ -0,0 +1,65 @@
+package sver_test
+
+import (
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("constraints", func() {
+	DescribeTable("checking versions",
+		func(constraint, version string, includePreReleases, expected bool) {
+			c, err := sver.ParseConstraint(constraint)
+			Expect(err).ToNot(HaveOccurred())
+
+			ok, err := c.Check(version, includePreReleases)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(Equal(expected), "%s %s", version, constraint)
+		},
+		Entry("release", ">=1.4, <2", "1.9.3", false, true),
+		Entry("release, no match", "^1.4 || ^3", "2.1.0", false, false),
+		Entry("partial upper bound", ">=1.4, <2", "2.5.0", false, false),
+		Entry("partial version", "1.2", "1.2.9", false, true),
+		Entry("development version, excluded", ">=1.4, <2", "1.5.0-20221001120000.3.g0123abcd", false, false),
+		Entry("development version, included", ">=1.4, <2", "1.5.0-20221001120000.3.g0123abcd", true, true),
+		Entry("pre-release, included like its release", "<2", "2.0.0-rc.1", true, false),
+		Entry("pre-release of a comparator", ">=1.4.0-rc.1", "1.4.0-rc.2", false, true),
+	)
+
+	It("rejects invalid constraints", func() {
+		_, err := sver.ParseConstraint(">=1 ||")
+		Expect(err).To(MatchError(ContainSubstring("invalid constraint '>=1 ||'")))
+	})
+
+	It("rejects invalid versions", func() {
+		c, err := sver.ParseConstraint(">=1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.String()).To(Equal(">=1"))
+
+		_, err = c.Check("latest", false)
+		var notSemver *sver.ErrNotSemver
+		Expect(errors.As(err, &notSemver)).To(BeTrue())
+	})
+
+	It("compares versions", func() {
+		for _, tc := range []struct {
+			a, b     string
+			expected int
+		}{
+			{"1.2.3", "1.2.3", 0},
+			{"v1.2.3", "1.10.0", -1},
+			{"2.0.0", "2.0.0-rc.1", 1},
+			{"1.0.0-alpha.10", "1.0.0-alpha.9", 1},
+		} {
+			result, err := sver.CompareVersions(tc.a, tc.b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(tc.expected), "%s %s", tc.a, tc.b)
+		}
+
+		_, err := sver.CompareVersions("1.2.3", "latest")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,114 @@
+package sver
+
+import (
+	"sort"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+)
+
+// Destination is an image repository that gets the same tags as other
+// destinations, with its own registry credentials.
+type Destination struct {
+	Repository  string
+	Credentials RegistryCredentials
+}
+
+// DestinationTags holds the tags that exist in a destination repository.
+type DestinationTags struct {
+	Destination Destination
+	Tags        []string
+}
+
+// VersionDrift describes a version that exists in some destination
+// repositories but not in others.
+type VersionDrift struct {
+	Version string
+	Present []string
+	Missing []string
+}
+
+// ListDestinationTags lists the existing tags of every destination, using the
+// credentials of each destination.
+func ListDestinationTags(destinations []Destination, opts ...RegistryOption) ([]DestinationTags, error) {
+	result := []DestinationTags{}
+	for _, destination := range destinations {
+		destOpts := append(append([]RegistryOption{}, opts...), WithCredentials(destination.Credentials))
+
+		tags, err := ListImageTags(destination.Repository, destOpts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list tags of [%s]", destination.Repository)
+		}
+
+		result = append(result, DestinationTags{
+			Destination: destination,
+			Tags:        tags,
+		})
+	}
+
+	return result, nil
+}
+
+// UnionTags returns every tag that exists in at least one destination.
+func UnionTags(sets []DestinationTags) []string {
+	tags := []string{}
+	for _, set := range sets {
+		tags = append(tags, set.Tags...)
+	}
+
+	result := unique(tags)
+	sort.Strings(result)
+
+	return result
+}
+
+// TagDrift returns the versions that don't exist in every destination, sorted
+// by precedence. Only full semantic versions are compared, floating tags like
+// `1.2` or `latest` are ignored.
+func TagDrift(sets []DestinationTags) []VersionDrift {
+	versions := map[string]*semver.Version{}
+	present := map[string]map[string]bool{}
+	for _, set := range sets {
+		for _, tag := range set.Tags {
+			if !regexSupportedVersionFormat.MatchString(tag) {
+				continue
+			}
+
+			v, err := semver.NewVersion(tag)
+			if err != nil {
+				continue
+			}
+
+			key := v.String()
+			versions[key] = v
+			if present[key] == nil {
+				present[key] = map[string]bool{}
+			}
+			present[key][set.Destination.Repository] = true
+		}
+	}
+
+	vs := []*semver.Version{}
+	for _, v := range versions {
+		vs = append(vs, v)
+	}
+	sort.Sort(semver.Collection(vs))
+
+	result := []VersionDrift{}
+	for _, v := range vs {
+		drift := VersionDrift{Version: v.String()}
+		for _, set := range sets {
+			if present[v.String()][set.Destination.Repository] {
+				drift.Present = append(drift.Present, set.Destination.Repository)
+			} else {
+				drift.Missing = append(drift.Missing, set.Destination.Repository)
+			}
+		}
+
+		if len(drift.Missing) > 0 {
+			result = append(result, drift)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,66 @@
+package sver_test
+
+import (
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("destinations", func() {
+	Context("listing tags", func() {
+		var registries []*svertest.Registry
+
+		newRepository := func(tags ...string) string {
+			registry := svertest.NewRegistry(GinkgoT())
+			registries = append(registries, registry)
+			registry.PushImage("org/image", tags...)
+
+			return registry.Repository("org/image")
+		}
+
+		AfterEach(func() {
+			for _, registry := range registries {
+				registry.Close()
+			}
+			registries = nil
+		})
+
+		It("returns the tags of every destination", func() {
+			first := newRepository("1.0.0", "1.1.0")
+			second := newRepository("1.0.0")
+
+			sets, err := sver.ListDestinationTags([]sver.Destination{{Repository: first}, {Repository: second}})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(sets).To(HaveLen(2))
+			Expect(sets[0].Tags).To(ConsistOf("1.0.0", "1.1.0"))
+			Expect(sets[1].Tags).To(ConsistOf("1.0.0"))
+		})
+	})
+
+	Context("comparing destinations", func() {
+		sets := []sver.DestinationTags{
+			{Destination: sver.Destination{Repository: "ghcr.io/org/image"}, Tags: []string{"1.0.0", "1.1.0", "1.1", "latest"}},
+			{Destination: sver.Destination{Repository: "docker.io/org/image"}, Tags: []string{"v1.0.0", "1.2.0", "latest"}},
+		}
+
+		It("returns the union of all tags", func() {
+			Expect(sver.UnionTags(sets)).To(Equal([]string{"1.0.0", "1.1", "1.1.0", "1.2.0", "latest", "v1.0.0"}))
+		})
+
+		It("reports versions missing from some destinations", func() {
+			drift := sver.TagDrift(sets)
+
+			Expect(drift).To(Equal([]sver.VersionDrift{
+				{Version: "1.1.0", Present: []string{"ghcr.io/org/image"}, Missing: []string{"docker.io/org/image"}},
+				{Version: "1.2.0", Present: []string{"docker.io/org/image"}, Missing: []string{"ghcr.io/org/image"}},
+			}))
+		})
+
+		It("reports no drift when all destinations agree", func() {
+			Expect(sver.TagDrift(sets[:1])).To(BeEmpty())
+		})
+	})
+})
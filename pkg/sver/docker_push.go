@@ -0,0 +1,119 @@
+package sver
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// PushResult describes what happened to a single tag when pushing an image.
+type PushResult struct {
+	// Reference is the fully qualified destination tag.
+	Reference string
+	// Digest is the digest of the source manifest the tag points to.
+	Digest string
+	// Pushed is false if this was a dry run.
+	Pushed bool
+}
+
+// PushTags copies the source image to every tag in the destination repository.
+// If the source lives in the destination repository, only the manifest is
+// written for each tag (a server-side retag); otherwise the image or index is
+// copied between registries. No docker daemon is involved.
+// After each push, the digest of the new tag is verified against the source.
+// With dryRun, the source is resolved but nothing is written.
+func PushTags(source, destRepo string, tags []string, dryRun bool, opts ...RegistryOption) ([]PushResult, error) {
+	srcRef, err := name.ParseReference(source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid source image [%s]", source)
+	}
+
+	repo, err := name.NewRepository(destRepo)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid repo name [%s]", destRepo)
+	}
+
+	o := newRegistryOptions(opts)
+	dstOpts, err := o.remoteOptions(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Explicit credentials are meant for the destination. A source in another
+	// registry uses the docker config.
+	srcOpts := dstOpts
+	if srcRef.Context().RegistryStr() != repo.RegistryStr() {
+		srcOpts, err = newRegistryOptions(nil).remoteOptions(srcRef.Context())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	desc, err := remote.Get(srcRef, srcOpts...)
+	if err != nil {
+		return nil, wrapRegistryError(err, "failed to get source image [%s]", source)
+	}
+
+	sameRepo := srcRef.Context().String() == repo.String()
+
+	results := []PushResult{}
+	for _, tag := range tags {
+		tagRef := repo.Tag(tag)
+		result := PushResult{
+			Reference: tagRef.String(),
+			Digest:    desc.Digest.String(),
+		}
+
+		if !dryRun {
+			if err := pushDescriptor(desc, tagRef, sameRepo, dstOpts); err != nil {
+				return results, wrapRegistryError(err, "failed to push [%s]", tagRef)
+			}
+
+			if err := verifyDigest(tagRef, desc, dstOpts); err != nil {
+				return results, err
+			}
+
+			result.Pushed = true
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func pushDescriptor(desc *remote.Descriptor, tag name.Tag, sameRepo bool, opts []remote.Option) error {
+	if sameRepo {
+		return remote.Tag(tag, desc, opts...)
+	}
+
+	if desc.MediaType.IsIndex() {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return err
+		}
+
+		return remote.WriteIndex(tag, index, opts...)
+	}
+
+	image, err := desc.Image()
+	if err != nil {
+		return err
+	}
+
+	return remote.Write(tag, image, opts...)
+}
+
+func verifyDigest(tag name.Tag, desc *remote.Descriptor, opts []remote.Option) error {
+	pushed, err := remote.Head(tag, opts...)
+	if err != nil {
+		return wrapRegistryError(err, "failed to verify [%s]", tag)
+	}
+
+	if pushed.Digest != desc.Digest {
+		return errors.Errorf("digest mismatch for [%s]: expected %s, got %s", tag, desc.Digest, pushed.Digest)
+	}
+
+	return nil
+}
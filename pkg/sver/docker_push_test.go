@@ -0,0 +1,82 @@
+package sver_test
+
+import (
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("push-tags", func() {
+	var (
+		registry *svertest.Registry
+		host     string
+		digest   v1.Hash
+	)
+
+	BeforeEach(func() {
+		registry = svertest.NewRegistry(GinkgoT())
+		host = registry.Host
+
+		var err error
+		digest, err = v1.NewHash(registry.PushImage("org/build", "ci"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		registry.Close()
+	})
+
+	tagDigest := func(ref string) v1.Hash {
+		tag, err := name.ParseReference(ref)
+		Expect(err).ToNot(HaveOccurred())
+
+		desc, err := remote.Head(tag)
+		Expect(err).ToNot(HaveOccurred())
+
+		return desc.Digest
+	}
+
+	It("retags the source image in the same repository", func() {
+		results, err := sver.PushTags(host+"/org/build:ci", host+"/org/build", []string{"1.2.0", "1.2", "latest"}, false)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(results).To(HaveLen(3))
+		for _, result := range results {
+			Expect(result.Pushed).To(BeTrue())
+			Expect(result.Digest).To(Equal(digest.String()))
+			Expect(tagDigest(result.Reference)).To(Equal(digest))
+		}
+	})
+
+	It("copies the source image to another repository", func() {
+		results, err := sver.PushTags(host+"/org/build:ci", host+"/org/release", []string{"1.2.0"}, false)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Reference).To(Equal(host + "/org/release:1.2.0"))
+		Expect(tagDigest(host + "/org/release:1.2.0")).To(Equal(digest))
+	})
+
+	It("doesn't push anything in a dry run", func() {
+		results, err := sver.PushTags(host+"/org/build:ci", host+"/org/release", []string{"1.2.0"}, true)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Pushed).To(BeFalse())
+		Expect(results[0].Digest).To(Equal(digest.String()))
+
+		tags, err := sver.ListImageTags(host + "/org/release")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tags).To(BeEmpty())
+	})
+
+	It("fails if the source image doesn't exist", func() {
+		_, err := sver.PushTags(host+"/org/build:missing", host+"/org/release", []string{"1.2.0"}, false)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -1,9 +1,16 @@
 package sver
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
@@ -14,42 +21,562 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 )
 
-func ImageTags(repoName, username, password string) ([]string, error) {
-	repo, err := name.NewRepository(repoName)
+var (
+	// Tails of versions calculated for commits that aren't tagged, or for dirty trees.
+	regexDevelopmentTail = regexp.MustCompile(`-\d{14}\.\d+\.g[0-9a-f]+|-dirty$`)
+	regexChannel         = regexp.MustCompile(`^[a-zA-Z][0-9a-zA-Z-]*$`)
+)
+
+// RegistryCredentials holds explicit credentials for a container registry.
+// A token takes precedence over a username and password. When no explicit
+// credentials are set, they are resolved from the docker config
+// (~/.docker/config.json and its credential helpers), and access is anonymous
+// if nothing is configured for the registry.
+type RegistryCredentials struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// IsEmpty returns true if no explicit credentials are set.
+func (c RegistryCredentials) IsEmpty() bool {
+	return c.Username == "" && c.Password == "" && c.Token == ""
+}
+
+func (c RegistryCredentials) authenticator(repo name.Repository) (authn.Authenticator, error) {
+	if c.Token != "" {
+		return &authn.Bearer{Token: c.Token}, nil
+	}
+
+	if !c.IsEmpty() {
+		return &authn.Basic{
+			Username: c.Username,
+			Password: c.Password,
+		}, nil
+	}
+
+	auth, err := authn.DefaultKeychain.Resolve(repo)
 	if err != nil {
-		return nil, errors.Wrapf(err, "invalid repo name [%s]", repoName)
+		return nil, errors.Wrapf(err, "failed to resolve credentials for [%s]", repo.RegistryStr())
+	}
+
+	return auth, nil
+}
+
+type registryOptions struct {
+	ctx            context.Context
+	credentials    RegistryCredentials
+	maxRetries     int
+	initialBackoff time.Duration
+	logf           func(format string, args ...interface{})
+	sleep          func(ctx context.Context, d time.Duration) error
+	pageSize       int
+	tagPrefix      string
+}
+
+// RegistryOption configures how sver talks to a container registry.
+type RegistryOption func(*registryOptions)
+
+// WithCredentials sets explicit credentials for the registry.
+func WithCredentials(credentials RegistryCredentials) RegistryOption {
+	return func(o *registryOptions) {
+		o.credentials = credentials
+	}
+}
+
+// WithRetry sets how many times requests that fail with a transient error, a
+// 429 or a 5xx status are retried, and the backoff before the first retry,
+// which doubles with each attempt. A Retry-After header from the registry takes
+// precedence over the backoff. Requests are retried 5 times by default,
+// starting with a one second backoff.
+func WithRetry(maxRetries int, initialBackoff time.Duration) RegistryOption {
+	return func(o *registryOptions) {
+		o.maxRetries = maxRetries
+		o.initialBackoff = initialBackoff
+	}
+}
+
+// WithLogger logs every request sent to the registry, and every retry.
+func WithLogger(logf func(format string, args ...interface{})) RegistryOption {
+	return func(o *registryOptions) {
+		o.logf = logf
+	}
+}
+
+// WithPageSize sets how many tags are requested per page when listing tags.
+// By default, the registry decides.
+func WithPageSize(pageSize int) RegistryOption {
+	return func(o *registryOptions) {
+		o.pageSize = pageSize
+	}
+}
+
+// WithTagPrefix only lists tags that start with the prefix. The OCI
+// distribution API has no filters, but it has the `last` parameter, which
+// registries use to skip the tags sorted before the prefix. The prefix is also
+// applied to the returned tags, for registries that ignore `last`. A tag equal
+// to the prefix itself isn't listed.
+func WithTagPrefix(prefix string) RegistryOption {
+	return func(o *registryOptions) {
+		o.tagPrefix = prefix
+	}
+}
+
+// WithContext sets the context of the requests to the registry, to cancel
+// them. It defaults to context.Background().
+func WithContext(ctx context.Context) RegistryOption {
+	return func(o *registryOptions) {
+		o.ctx = ctx
 	}
+}
 
-	tags, err := remote.List(repo, remote.WithAuth(&authn.Basic{
+// withSleep replaces how the transport waits before a retry, for tests.
+func withSleep(sleep func(ctx context.Context, d time.Duration) error) RegistryOption {
+	return func(o *registryOptions) {
+		o.sleep = sleep
+	}
+}
+
+func newRegistryOptions(opts []RegistryOption) *registryOptions {
+	o := &registryOptions{
+		ctx:            context.Background(),
+		maxRetries:     defaultMaxRetries,
+		initialBackoff: defaultInitialBackoff,
+		logf:           func(string, ...interface{}) {},
+		sleep:          sleepContext,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+func (o *registryOptions) authenticator(repo name.Repository) (authn.Authenticator, error) {
+	return o.credentials.authenticator(repo)
+}
+
+func (o *registryOptions) remoteOptions(repo name.Repository) ([]remote.Option, error) {
+	auth, err := o.authenticator(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return []remote.Option{
+		remote.WithContext(o.ctx),
+		remote.WithAuth(auth),
+		remote.WithTransport(newRetryTransport(o)),
+	}, nil
+}
+
+// ImageTags lists all tags of an image repository using basic authentication.
+// If both username and password are empty, credentials are resolved from the
+// docker config.
+func ImageTags(repoName, username, password string) ([]string, error) {
+	return ListImageTags(repoName, WithCredentials(RegistryCredentials{
 		Username: username,
 		Password: password,
 	}))
+}
+
+// ListImageTags lists all tags of an image repository, following pagination.
+// A repository that doesn't exist has no tags.
+func ListImageTags(repoName string, opts ...RegistryOption) ([]string, error) {
+	repo, err := name.NewRepository(repoName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid repo name [%s]", repoName)
+	}
+
+	o := newRegistryOptions(opts)
+	auth, err := o.authenticator(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := newRetryTransport(o)
+	tags, err := listTags(repo, auth, rt, o)
 	if err != nil {
-		if tErr, ok := err.(*transport.Error); ok {
+		if rlErr := rt.lastRateLimit(); rlErr != nil {
+			return nil, rlErr
+		}
+
+		var tErr *transport.Error
+		if errors.As(err, &tErr) {
 			switch tErr.StatusCode {
 			case http.StatusUnauthorized:
-				return nil, errors.Wrap(err, "authentication to docker registry failed")
+				return nil, wrapKind(ErrRegistryAuth, err, "authentication to docker registry failed")
 			case http.StatusNotFound:
 				return []string{}, nil
 			}
 		}
 
-		return nil, errors.Wrap(err, "failed to list tags from registry")
+		return nil, wrapRegistryError(err, "failed to list tags from registry")
+	}
+
+	return tags, nil
+}
+
+func listTags(repo name.Repository, auth authn.Authenticator, rt http.RoundTripper, o *registryOptions) ([]string, error) {
+	ctx := o.ctx
+	scopes := []string{repo.Scope(transport.PullScope)}
+	tr, err := transport.NewWithContext(ctx, repo.Registry, auth, rt, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	if o.pageSize > 0 {
+		query.Set("n", strconv.Itoa(o.pageSize))
+	}
+	if o.tagPrefix != "" {
+		query.Set("last", o.tagPrefix)
+	}
+
+	uri := &url.URL{
+		Scheme:   repo.Registry.Scheme(),
+		Host:     repo.RegistryStr(),
+		Path:     fmt.Sprintf("/v2/%s/tags/list", repo.RepositoryStr()),
+		RawQuery: query.Encode(),
+	}
+
+	client := &http.Client{Transport: tr}
+	tags := []string{}
+	for uri != nil {
+		page, next, err := listTagsPage(ctx, client, uri)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range page {
+			if strings.HasPrefix(tag, o.tagPrefix) {
+				tags = append(tags, tag)
+			}
+		}
+
+		uri = next
 	}
 
 	return tags, nil
 }
 
+func listTagsPage(ctx context.Context, client *http.Client, uri *url.URL) ([]string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), http.NoBody)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return nil, nil, err
+	}
+
+	page := struct {
+		Tags []string `json:"tags"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse tag list")
+	}
+
+	next, err := nextPage(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return page.Tags, next, nil
+}
+
+// nextPage returns the URL in the Link header of a response, if there is one.
+func nextPage(resp *http.Response) (*url.URL, error) {
+	link := resp.Header.Get("Link")
+	if link == "" {
+		return nil, nil
+	}
+
+	start := strings.Index(link, "<")
+	end := strings.Index(link, ">")
+	if start == -1 || end < start {
+		return nil, errors.Errorf("failed to parse link header [%s]", link)
+	}
+
+	next, err := url.Parse(link[start+1 : end])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse link header [%s]", link)
+	}
+
+	return resp.Request.URL.ResolveReference(next), nil
+}
+
+// TagOptions configure which floating tags are calculated for a version.
+type TagOptions struct {
+	// Channels adds floating tags for the channel of a pre-release version.
+	// For `1.4.0-rc.3` these are `1.4.0-rc`, `1.4-rc` and `rc`, each of them
+	// only if the version is the newest of its channel in that series.
+	Channels bool
+	// Edge adds the `edge` tag if the version is the newest of all existing
+	// versions, including pre-releases.
+	Edge bool
+	// Policy sets the floating tags of release versions. If nil, the
+	// DefaultTagPolicy is used.
+	Policy TagPolicy
+	// Variant calculates the tags of an image variant, like `alpine`. Only
+	// existing tags with the `-<variant>` suffix are taken into account, and
+	// all calculated tags get the suffix, except `latest`, which becomes the
+	// variant name itself.
+	Variant string
+	// Variants are the variants the image is built in, like `alpine` or
+	// `distroless`. Without Variant, existing tags of these variants are
+	// ignored, so `1.4.0-distroless` isn't taken for a pre-release of 1.4.0.
+	Variants []string
+}
+
+// CalculateTagsForVersion returns the tags to push for a version, given the
+// tags that already exist. Besides the version itself, these are `X.Y`, `X`
+// and `latest` if the version is higher than all existing versions, including
+// pre-releases, in the minor series, the major series and overall. Development
+// and pre-release versions only get the full version tag.
 func CalculateTagsForVersion(version string, tags []string) ([]string, error) {
-	major, minor, _, tail, err := Parts(version)
+	return CalculateTags(version, tags, TagOptions{})
+}
+
+// TagDecision records whether a tag was added for a version, and why.
+type TagDecision struct {
+	Tag   string `json:"tag"`
+	Added bool   `json:"added"`
+	// BlockedBy is the existing tag of the newer version that kept the tag
+	// from being added.
+	BlockedBy string `json:"blockedBy,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+func (d TagDecision) String() string {
+	if d.Added {
+		return fmt.Sprintf("%s: added, %s", d.Tag, d.Reason)
+	}
+
+	return fmt.Sprintf("%s: skipped, %s", d.Tag, d.Reason)
+}
+
+// TagsDerivation records how CalculateTags derived the tags of a version.
+type TagsDerivation struct {
+	Tags      []string      `json:"tags"`
+	Decisions []TagDecision `json:"decisions"`
+}
+
+// CalculateTags works like CalculateTagsForVersion, with a configurable policy
+// for the floating tags of releases and optional floating tags for pre-releases.
+func CalculateTags(version string, tags []string, opts TagOptions) ([]string, error) {
+	d, err := DeriveTags(version, tags, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Tags, nil
+}
+
+// DeriveTags works like CalculateTags, and returns a decision for each tag
+// that was considered, with the existing tag that blocked it if it wasn't
+// added.
+func DeriveTags(version string, tags []string, opts TagOptions) (*TagsDerivation, error) {
+	if opts.Variant != "" {
+		return deriveVariantTags(version, tags, opts)
+	}
+
+	major, minor, patch, tail, err := Parts(version)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse version")
 	}
 
+	d := &TagsDerivation{}
+	if regexDevelopmentTail.MatchString(tail) {
+		d.Decisions = []TagDecision{{Tag: version, Added: true, Reason: "a development version only gets its own tag"}}
+		d.Tags = []string{version}
+		return d, nil
+	}
+
+	parsedVersion, err := semver.NewVersion(version)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse version")
+	}
+
+	vs := parseVersions(withoutVariants(tags, opts.Variants))
+
 	if tail != "" {
-		return []string{version}, nil
+		d.Decisions = append(d.Decisions, TagDecision{Tag: version, Added: true, Reason: "a pre-release gets its own tag, and channel tags if they're enabled"})
+
+		channel := preReleaseChannel(parsedVersion)
+		if opts.Channels && channel != "" {
+			inChannel := func(v *semver.Version) bool {
+				return preReleaseChannel(v) == channel
+			}
+
+			d.Decisions = append(d.Decisions,
+				decideLatest(fmt.Sprintf("%d.%d.%d-%s", major, minor, patch, channel), parsedVersion, vs,
+					func(v *semver.Version) bool { return inChannel(v) && samePatch(v, parsedVersion) },
+					fmt.Sprintf("%s pre-release of %d.%d.%d", channel, major, minor, patch)),
+				decideLatest(fmt.Sprintf("%d.%d-%s", major, minor, channel), parsedVersion, vs,
+					func(v *semver.Version) bool { return inChannel(v) && sameMinor(v, parsedVersion) },
+					fmt.Sprintf("%s pre-release of %d.%d", channel, major, minor)),
+				decideLatest(channel, parsedVersion, vs, inChannel, fmt.Sprintf("%s pre-release", channel)),
+			)
+		}
+	} else {
+		d.Decisions = append(d.Decisions, TagDecision{Tag: version, Added: true, Reason: "a release gets its own tag, and the floating tags of the tag policy"})
+
+		policy := opts.Policy
+		if policy == nil {
+			policy = DefaultTagPolicy()
+		}
+
+		floating, err := policy.apply(parsedVersion, vs)
+		if err != nil {
+			return nil, err
+		}
+
+		d.Decisions = append(d.Decisions, floating...)
+	}
+
+	if opts.Edge {
+		d.Decisions = append(d.Decisions, decideNewest("edge", parsedVersion, vs, func(*semver.Version) bool { return true }, "version"))
+	}
+
+	result := []string{}
+	for _, decision := range d.Decisions {
+		if decision.Added {
+			result = append(result, decision.Tag)
+		}
 	}
+	d.Tags = unique(result)
 
+	return d, nil
+}
+
+// decideNewest adds a tag if no existing version in scope is newer than v.
+// The scope describes these versions, like `rc pre-release of 1.2`.
+func decideNewest(tag string, v *semver.Version, existing []*semver.Version, inScope func(*semver.Version) bool, scope string) TagDecision {
+	newer := newestInScope(v, existing, inScope, false)
+	if newer == nil {
+		return TagDecision{Tag: tag, Added: true, Reason: fmt.Sprintf("no existing %s is newer", scope)}
+	}
+
+	return TagDecision{
+		Tag:       tag,
+		BlockedBy: newer.Original(),
+		Reason:    fmt.Sprintf("%s is a newer %s", newer.Original(), scope),
+	}
+}
+
+// decideLatest adds a floating tag of a release or a channel tag of a
+// pre-release if v is higher than all existing versions in scope, including
+// pre-releases. An existing tag of v itself blocks it too, so running sver
+// again for a version that was already pushed doesn't move its floating tags.
+func decideLatest(tag string, v *semver.Version, existing []*semver.Version, inScope func(*semver.Version) bool, scope string) TagDecision {
+	newer := newestInScope(v, existing, inScope, true)
+	if newer == nil {
+		return TagDecision{Tag: tag, Added: true, Reason: fmt.Sprintf("no existing %s is newer", scope)}
+	}
+
+	if newer.Equal(v) {
+		return TagDecision{
+			Tag:       tag,
+			BlockedBy: newer.Original(),
+			Reason:    fmt.Sprintf("%s already exists", newer.Original()),
+		}
+	}
+
+	return TagDecision{
+		Tag:       tag,
+		BlockedBy: newer.Original(),
+		Reason:    fmt.Sprintf("%s is a newer %s", newer.Original(), scope),
+	}
+}
+
+func deriveVariantTags(version string, tags []string, opts TagOptions) (*TagsDerivation, error) {
+	variant := opts.Variant
+	if !regexChannel.MatchString(variant) {
+		return nil, errors.Errorf("'%s' isn't a valid variant name", variant)
+	}
+
+	suffix := "-" + variant
+	variantTags := []string{}
+	for _, tag := range tags {
+		if strings.HasSuffix(tag, suffix) {
+			variantTags = append(variantTags, strings.TrimSuffix(tag, suffix))
+		}
+	}
+
+	opts.Variant = ""
+	d, err := DeriveTags(version, variantTags, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rename := func(tag string) string {
+		if tag == "latest" {
+			return variant
+		}
+		return tag + suffix
+	}
+
+	for i, tag := range d.Tags {
+		d.Tags[i] = rename(tag)
+	}
+
+	for i, decision := range d.Decisions {
+		d.Decisions[i].Tag = rename(decision.Tag)
+		if decision.BlockedBy != "" {
+			d.Decisions[i].BlockedBy = decision.BlockedBy + suffix
+			d.Decisions[i].Reason = decision.BlockedBy + suffix + strings.TrimPrefix(decision.Reason, decision.BlockedBy)
+		}
+	}
+
+	return d, nil
+}
+
+// withoutVariants returns the tags that don't belong to one of the variants.
+func withoutVariants(tags, variants []string) []string {
+	if len(variants) == 0 {
+		return tags
+	}
+
+	result := []string{}
+	for _, tag := range tags {
+		isVariant := false
+		for _, variant := range variants {
+			if tag == variant || strings.HasSuffix(tag, "-"+variant) {
+				isVariant = true
+				break
+			}
+		}
+
+		if !isVariant {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+func unique(tags []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, tag := range tags {
+		if seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	return result
+}
+
+func parseVersions(tags []string) []*semver.Version {
 	vs := []*semver.Version{}
 	for _, r := range tags {
 		v, err := semver.NewVersion(r)
@@ -60,47 +587,61 @@ func CalculateTagsForVersion(version string, tags []string) ([]string, error) {
 		vs = append(vs, v)
 	}
 
-	result := []string{version}
 	sort.Sort(semver.Collection(vs))
 
-	parsedVersion, err := semver.NewVersion(version)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse version")
-	}
+	return vs
+}
 
-	doMajor := true
-	doMinor := true
-	for idx := range vs {
-		if parsedVersion.GreaterThan(vs[idx]) {
+// newestInScope returns the newest existing version in scope if it's higher
+// than v, or equal to v with orEqual, or nil. Existing versions are sorted.
+// Floating tags like `1.2` parse like `1.2.0`, so a full version tag is
+// preferred among equal versions.
+func newestInScope(v *semver.Version, existing []*semver.Version, inScope func(*semver.Version) bool, orEqual bool) *semver.Version {
+	var newest *semver.Version
+	for i := len(existing) - 1; i >= 0; i-- {
+		e := existing[i]
+		if !inScope(e) {
 			continue
 		}
 
-		if vs[idx].Major() == parsedVersion.Major() {
-			doMajor = false
+		if newest == nil {
+			if e.LessThan(v) || !orEqual && e.Equal(v) {
+				return nil
+			}
+			newest = e
+		} else if !e.Equal(newest) {
+			break
 		}
 
-		if vs[idx].Minor() == parsedVersion.Minor() {
-			doMinor = false
+		if regexSupportedVersionFormat.MatchString(e.Original()) {
+			return e
 		}
-
-		break
 	}
 
-	if doMinor {
-		result = append(result, fmt.Sprintf("%d.%d", major, minor))
-	}
+	return newest
+}
 
-	if doMajor {
-		result = append(result, fmt.Sprintf("%d", major))
-	}
+func isRelease(v *semver.Version) bool {
+	return v.Prerelease() == ""
+}
 
-	var latestExistingTag *semver.Version
-	if len(vs) > 0 {
-		latestExistingTag = vs[len(vs)-1]
-	}
-	if latestExistingTag == nil || parsedVersion.GreaterThan(latestExistingTag) {
-		result = append(result, "latest")
+func sameMinor(a, b *semver.Version) bool {
+	return a.Major() == b.Major() && a.Minor() == b.Minor()
+}
+
+func samePatch(a, b *semver.Version) bool {
+	return sameMinor(a, b) && a.Patch() == b.Patch()
+}
+
+// preReleaseChannel returns the channel of a pre-release version, which is its
+// first pre-release identifier without trailing digits (`rc` for `rc.3` or
+// `beta2`). Numeric identifiers don't name a channel.
+func preReleaseChannel(v *semver.Version) string {
+	identifier := strings.SplitN(v.Prerelease(), ".", 2)[0]
+	channel := strings.TrimRight(identifier, "0123456789")
+	if channel == "" || !regexChannel.MatchString(channel) {
+		return ""
 	}
 
-	return result, nil
+	return channel
 }
@@ -1,28 +1,115 @@
 package sver_test
 
 import (
+	"os"
+	"path/filepath"
+
 	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("registry-versions", func() {
 	Context("public image", func() {
+		var registry *svertest.Registry
+
+		BeforeEach(func() {
+			registry = svertest.NewRegistry(GinkgoT())
+			registry.PushImage("org/image", "1.0.0", "1.1.0", "1.1", "1", "latest")
+		})
+
+		AfterEach(func() {
+			registry.Close()
+		})
+
 		It("reading tags from the repo doesn't error", func() {
-			tags, err := sver.ImageTags("ghcr.io/aserto-dev/sver", "", "")
+			tags, err := sver.ImageTags(registry.Repository("org/image"), "", "")
 
 			Expect(err).ToNot(HaveOccurred())
 			Expect(tags).ToNot(HaveLen(0))
 		})
 
 		It("returns all version tags", func() {
-			tags, err := sver.ImageTags("ghcr.io/aserto-dev/sver", "", "")
+			tags, err := sver.ImageTags(registry.Repository("org/image"), "", "")
 
 			Expect(err).ToNot(HaveOccurred())
-			Expect(tags).ToNot(HaveLen(0))
+			Expect(tags).To(ConsistOf("1.0.0", "1.1.0", "1.1", "1", "latest"))
+		})
+
+		It("has no tags for a repository that doesn't exist", func() {
+			tags, err := sver.ImageTags(registry.Repository("org/missing"), "", "")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tags).To(BeEmpty())
+		})
+	})
+
+	Context("registry credentials", func() {
+		var (
+			repo            name.Repository
+			dockerConfig    string
+			oldDockerConfig string
+		)
+
+		BeforeEach(func() {
+			var err error
+			repo, err = name.NewRepository("registry.example.com/org/image")
+			Expect(err).ToNot(HaveOccurred())
+
+			// Point the docker config to an empty directory so the host config is never used.
+			dockerConfig, err = os.MkdirTemp("", "sver-docker")
+			Expect(err).ToNot(HaveOccurred())
+
+			oldDockerConfig = os.Getenv("DOCKER_CONFIG")
+			os.Setenv("DOCKER_CONFIG", dockerConfig)
+		})
+
+		AfterEach(func() {
+			os.Setenv("DOCKER_CONFIG", oldDockerConfig)
+			Expect(os.RemoveAll(dockerConfig)).To(Succeed())
+		})
+
+		authorization := func(credentials sver.RegistryCredentials) *authn.AuthConfig {
+			auth, err := sver.RegistryAuthenticator(credentials, repo)
+			Expect(err).ToNot(HaveOccurred())
+
+			cfg, err := auth.Authorization()
+			Expect(err).ToNot(HaveOccurred())
+
+			return cfg
+		}
+
+		It("uses a bearer token when one is set", func() {
+			cfg := authorization(sver.RegistryCredentials{Username: "user", Password: "secret", Token: "token"})
+			Expect(cfg.RegistryToken).To(Equal("token"))
+			Expect(cfg.Password).To(BeEmpty())
+		})
+
+		It("uses basic authentication when a username and password are set", func() {
+			cfg := authorization(sver.RegistryCredentials{Username: "user", Password: "secret"})
+			Expect(cfg.Username).To(Equal("user"))
+			Expect(cfg.Password).To(Equal("secret"))
 		})
 
+		It("falls back to the docker config", func() {
+			config := `{"auths": {"registry.example.com": {"username": "docker", "password": "config"}}}`
+			err := os.WriteFile(filepath.Join(dockerConfig, "config.json"), []byte(config), 0600)
+			Expect(err).ToNot(HaveOccurred())
+
+			cfg := authorization(sver.RegistryCredentials{})
+			Expect(cfg.Username).To(Equal("docker"))
+			Expect(cfg.Password).To(Equal("config"))
+		})
+
+		It("is anonymous when nothing is configured", func() {
+			cfg := authorization(sver.RegistryCredentials{})
+			Expect(*cfg).To(Equal(authn.AuthConfig{}))
+		})
 	})
 
 	Context("when it's a development release", func() {
@@ -127,5 +214,196 @@ var _ = Describe("registry-versions", func() {
 				Expect(tags).To(ContainElement("latest"))
 			})
 		})
+
+		// The results of the original implementation, which the default tag
+		// policy must keep.
+		DescribeTable("keeps the original results",
+			func(version string, existingTags []string, expected []string) {
+				tags, err := sver.CalculateTagsForVersion(version, existingTags)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(tags).To(Equal(expected))
+			},
+			Entry("newer pre-release", "1.3.0", []string{"1.2.0", "1.4.0-rc.1"}, []string{"1.3.0", "1.3"}),
+			Entry("already pushed", "1.0.1", []string{"1.0.0", "1.0.1", "1.0", "1", "latest"}, []string{"1.0.1"}),
+			Entry("patch of an older minor", "1.0.1", []string{"1.0.0", "1.1.0", "2.1.0"}, []string{"1.0.1", "1.0"}),
+			Entry("newest", "2.1.0", []string{"1.0.0", "1.1.0"}, []string{"2.1.0", "2.1", "2", "latest"}),
+			Entry("newest, already pushed", "2.1.0", []string{"1.0.0", "1.1.0", "2.1.0"}, []string{"2.1.0"}),
+			Entry("after its own pre-release", "1.2.0", []string{"1.2.0-rc.1", "1.1.0"}, []string{"1.2.0", "1.2", "1", "latest"}),
+			Entry("new major after its pre-release", "2.0.0", []string{"2.0.0-rc.1", "1.9.0"}, []string{"2.0.0", "2.0", "2", "latest"}),
+			Entry("older patch", "1.2.3", []string{"v1.2.5", "1.3.0", "2.0.0-rc.1"}, []string{"1.2.3"}),
+			Entry("existing floating tags", "1.4.0", []string{"1.3.0", "1.4", "1", "latest"}, []string{"1.4.0"}),
+			Entry("older major", "1.3.1", []string{"0.9.0", "1.2.0", "1.0.0", "2.0.0"}, []string{"1.3.1", "1.3", "1"}),
+			Entry("first release", "0.1.0", []string{}, []string{"0.1.0", "0.1", "0", "latest"}),
+			Entry("pre-release", "1.4.0-rc.1", []string{"1.3.0"}, []string{"1.4.0-rc.1"}),
+		)
+	})
+
+	Context("with pre-release channels", func() {
+		opts := sver.TagOptions{Channels: true}
+
+		Context("if it's the newest pre-release of its channel", func() {
+			It("returns the channel tags", func() {
+				version := "1.4.0-rc.3"
+				existingTags := []string{"1.3.0", "1.4.0-rc.2", "1.4.0-beta.5"}
+
+				tags, err := sver.CalculateTags(version, existingTags, opts)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(tags).To(Equal([]string{"1.4.0-rc.3", "1.4.0-rc", "1.4-rc", "rc"}))
+			})
+		})
+
+		Context("if a newer pre-release exists in the channel", func() {
+			It("only returns the channel tags it's the newest for", func() {
+				version := "1.4.1-rc.1"
+				existingTags := []string{"1.4.0-rc.2", "1.5.0-rc.1"}
+
+				tags, err := sver.CalculateTags(version, existingTags, opts)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(tags).To(Equal([]string{"1.4.1-rc.1", "1.4.1-rc", "1.4-rc"}))
+			})
+		})
+
+		Context("if the pre-release was already pushed", func() {
+			It("doesn't move the channel tags", func() {
+				version := "1.4.0-rc.3"
+				existingTags := []string{"1.3.0", "1.4.0-rc.3", "1.4.0-rc", "1.4-rc", "rc"}
+
+				tags, err := sver.CalculateTags(version, existingTags, opts)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(tags).To(Equal([]string{"1.4.0-rc.3"}))
+			})
+		})
+
+		Context("if it's a development version", func() {
+			It("only returns the full version tag", func() {
+				version := "1.4.0-rc.3-20201027184820.3.g4fc2e9e5"
+
+				tags, err := sver.CalculateTags(version, []string{}, opts)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(tags).To(Equal([]string{version}))
+			})
+		})
+
+		Context("if the pre-release has no channel", func() {
+			It("only returns the full version tag", func() {
+				version := "1.4.0-1"
+
+				tags, err := sver.CalculateTags(version, []string{}, opts)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(tags).To(Equal([]string{version}))
+			})
+		})
+	})
+
+	Context("with the edge tag", func() {
+		opts := sver.TagOptions{Edge: true}
+
+		It("returns edge if the version is the newest including pre-releases", func() {
+			tags, err := sver.CalculateTags("1.4.0-rc.1", []string{"1.3.0", "1.4.0-beta.1"}, opts)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(tags).To(Equal([]string{"1.4.0-rc.1", "edge"}))
+		})
+
+		It("doesn't return edge if a newer pre-release exists", func() {
+			tags, err := sver.CalculateTags("1.3.1", []string{"1.3.0", "1.4.0-beta.1"}, opts)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(tags).To(Equal([]string{"1.3.1", "1.3"}))
+		})
+	})
+
+	Context("with a variant", func() {
+		existingTags := []string{
+			"1.2.0", "1.3.0", "1.3", "1", "latest",
+			"1.2.0-alpine", "1.2-alpine", "1-alpine", "alpine",
+			"1.4.0-distroless",
+		}
+
+		It("calculates tags within the variant", func() {
+			tags, err := sver.CalculateTags("1.2.1", existingTags, sver.TagOptions{Variant: "alpine"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(tags).To(Equal([]string{"1.2.1-alpine", "1.2-alpine", "1-alpine", "alpine"}))
+		})
+
+		It("ignores the tags of known variants without a variant", func() {
+			tags, err := sver.CalculateTags("1.3.1", existingTags, sver.TagOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tags).To(Equal([]string{"1.3.1", "1.3"}))
+
+			tags, err = sver.CalculateTags("1.3.1", existingTags, sver.TagOptions{Variants: []string{"alpine", "distroless"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tags).To(Equal([]string{"1.3.1", "1.3", "1", "latest"}))
+		})
+
+		It("ignores other variants", func() {
+			tags, err := sver.CalculateTags("1.3.1", existingTags, sver.TagOptions{Variant: "fips"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(tags).To(Equal([]string{"1.3.1-fips", "1.3-fips", "1-fips", "fips"}))
+		})
+
+		It("only returns the full version tag for development versions", func() {
+			version := "1.3.1-20201027184820.3.g4fc2e9e5"
+
+			tags, err := sver.CalculateTags(version, existingTags, sver.TagOptions{Variant: "alpine"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(tags).To(Equal([]string{version + "-alpine"}))
+		})
+
+		It("fails with an invalid variant name", func() {
+			_, err := sver.CalculateTags("1.3.1", existingTags, sver.TagOptions{Variant: "al:pine"})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("names the variant tags that block floating tags", func() {
+			d, err := sver.DeriveTags("1.1.0", existingTags, sver.TagOptions{Variant: "alpine"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Decisions).To(ContainElement(sver.TagDecision{
+				Tag:       "alpine",
+				BlockedBy: "1.2.0-alpine",
+				Reason:    "1.2.0-alpine is a newer version",
+			}))
+		})
+	})
+
+	Context("explaining tags", func() {
+		It("names the existing tag that blocks each floating tag", func() {
+			d, err := sver.DeriveTags("1.2.3", []string{"v1.2.5", "1.3.0", "2.0.0-rc.1"}, sver.TagOptions{Edge: true})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Tags).To(Equal([]string{"1.2.3"}))
+			Expect(d.Decisions).To(Equal([]sver.TagDecision{
+				{Tag: "1.2.3", Added: true, Reason: "a release gets its own tag, and the floating tags of the tag policy"},
+				{Tag: "1.2", BlockedBy: "v1.2.5", Reason: "v1.2.5 is a newer version of 1.2"},
+				{Tag: "1", BlockedBy: "1.3.0", Reason: "1.3.0 is a newer version of 1"},
+				{Tag: "latest", BlockedBy: "2.0.0-rc.1", Reason: "2.0.0-rc.1 is a newer version"},
+				{Tag: "edge", BlockedBy: "2.0.0-rc.1", Reason: "2.0.0-rc.1 is a newer version"},
+			}))
+		})
+
+		It("explains that an existing version keeps its floating tags", func() {
+			d, err := sver.DeriveTags("1.0.1", []string{"1.0.0", "1.0.1"}, sver.TagOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Decisions[1].String()).To(Equal("1.0: skipped, 1.0.1 already exists"))
+		})
+
+		It("explains the channel tags of a pre-release", func() {
+			d, err := sver.DeriveTags("1.4.0-rc.2", []string{"1.4.0-rc.1", "1.5.0-rc.1"}, sver.TagOptions{Channels: true})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Tags).To(Equal([]string{"1.4.0-rc.2", "1.4.0-rc", "1.4-rc"}))
+			Expect(d.Decisions[3].String()).To(Equal("rc: skipped, 1.5.0-rc.1 is a newer rc pre-release"))
+		})
 	})
 })
@@ -0,0 +1,216 @@
+package sver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxRetries     = 5
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+	// Longer waits requested by the registry with Retry-After aren't honored,
+	// the request fails with a RateLimitError instead.
+	maxRetryAfter = time.Minute
+)
+
+// RateLimitError is returned when a registry keeps rejecting requests with
+// 429 Too Many Requests.
+type RateLimitError struct {
+	Registry string
+	// RetryAfter is how long the registry asked to wait, if it said so.
+	RetryAfter time.Duration
+	// Limit and Remaining are the rate limit headers some registries, like
+	// Docker Hub, return.
+	Limit     string
+	Remaining string
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("registry [%s] is rate limiting requests", e.Registry)
+	if e.Limit != "" {
+		msg += fmt.Sprintf(" (limit %s, remaining %s)", e.Limit, e.Remaining)
+	}
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf("; retry after %s", e.RetryAfter)
+	}
+
+	return msg
+}
+
+// retryTransport retries requests that fail with a transient network error
+// (a timeout or a dropped connection), a 429 or a 5xx status, with exponential
+// backoff. A Retry-After header from the registry takes precedence over the
+// backoff.
+type retryTransport struct {
+	inner          http.RoundTripper
+	maxRetries     int
+	initialBackoff time.Duration
+	logf           func(format string, args ...interface{})
+	sleep          func(ctx context.Context, d time.Duration) error
+
+	// The transport is shared by concurrent requests, like the layer uploads
+	// of remote.Write.
+	mu sync.Mutex
+	// rateLimited is set when the registry rate limited a request and
+	// retries didn't help.
+	rateLimited *RateLimitError
+}
+
+func newRetryTransport(o *registryOptions) *retryTransport {
+	return &retryTransport{
+		inner:          remote.DefaultTransport,
+		maxRetries:     o.maxRetries,
+		initialBackoff: o.initialBackoff,
+		logf:           o.logf,
+		sleep:          o.sleep,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := t.initialBackoff
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+
+		start := time.Now()
+		resp, err := t.inner.RoundTrip(r)
+		if err != nil {
+			t.logf("%s %s: %s", req.Method, req.URL, err)
+		} else {
+			t.logf("%s %s: %s (%s)", req.Method, req.URL, resp.Status, time.Since(start).Round(time.Millisecond))
+		}
+
+		if attempt >= t.maxRetries || !retryable(resp, err) || (req.Body != nil && req.GetBody == nil) {
+			return t.giveUp(req, resp, err)
+		}
+
+		wait := backoff
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp); ok {
+				if retryAfter > maxRetryAfter {
+					return t.giveUp(req, resp, err)
+				}
+				wait = retryAfter
+			}
+
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		t.logf("retrying in %s (attempt %d of %d)", wait, attempt+1, t.maxRetries)
+
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+
+		backoff *= 2
+		if backoff > defaultMaxBackoff {
+			backoff = defaultMaxBackoff
+		}
+	}
+}
+
+func (t *retryTransport) giveUp(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		t.mu.Lock()
+		t.rateLimited = rateLimitError(req.URL.Host, resp)
+		t.mu.Unlock()
+	}
+
+	return resp, err
+}
+
+// lastRateLimit returns the error of the last request the registry rate
+// limited, or nil.
+func (t *retryTransport) lastRateLimit() *RateLimitError {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.rateLimited
+}
+
+// sleepContext waits for d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.Is(err, io.ErrUnexpectedEOF) ||
+			errors.Is(err, syscall.ECONNRESET) ||
+			(errors.As(err, &netErr) && netErr.Timeout())
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// parseRetryAfter reads the Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+
+		return wait, true
+	}
+
+	return 0, false
+}
+
+func rateLimitError(registry string, resp *http.Response) *RateLimitError {
+	rlErr := &RateLimitError{
+		Registry:  registry,
+		Limit:     resp.Header.Get("RateLimit-Limit"),
+		Remaining: resp.Header.Get("RateLimit-Remaining"),
+	}
+	rlErr.RetryAfter, _ = parseRetryAfter(resp)
+
+	return rlErr
+}
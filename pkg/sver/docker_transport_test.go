@@ -0,0 +1,171 @@
+package sver_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeRegistry serves the tag list of a single repository, and fails the first
+// requests with the configured status.
+type fakeRegistry struct {
+	mu         sync.Mutex
+	tags       []string
+	failures   int
+	failStatus int
+	retryAfter string
+	requests   []string
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, r.URL.RequestURI())
+
+	if f.failures > 0 {
+		f.failures--
+		if f.retryAfter != "" {
+			w.Header().Set("Retry-After", f.retryAfter)
+		}
+		w.Header().Set("RateLimit-Limit", "100")
+		w.Header().Set("RateLimit-Remaining", "0")
+		w.WriteHeader(f.failStatus)
+		return
+	}
+
+	if r.URL.Path == "/v2/" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.URL.Path != "/v2/org/image/tags/list" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	tags := append([]string{}, f.tags...)
+	sort.Strings(tags)
+
+	last := r.URL.Query().Get("last")
+	start := sort.SearchStrings(tags, last)
+	if start < len(tags) && tags[start] == last {
+		start++
+	}
+	tags = tags[start:]
+
+	if n, err := strconv.Atoi(r.URL.Query().Get("n")); err == nil && n < len(tags) {
+		next := fmt.Sprintf("/v2/org/image/tags/list?n=%d&last=%s", n, tags[n-1])
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next))
+		tags = tags[:n]
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "org/image", "tags": tags})
+}
+
+var _ = Describe("registry-transport", func() {
+	var (
+		fake   *fakeRegistry
+		server *httptest.Server
+		repo   string
+	)
+
+	BeforeEach(func() {
+		fake = &fakeRegistry{tags: []string{"0.9.0", "1.0.0", "1.1.0", "1.2.0", "2.0.0", "latest"}}
+		server = httptest.NewServer(fake)
+		repo = strings.TrimPrefix(server.URL, "http://") + "/org/image"
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	fastRetry := sver.WithRetry(3, time.Millisecond)
+
+	It("follows pagination", func() {
+		tags, err := sver.ListImageTags(repo, sver.WithPageSize(2), fastRetry)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(tags).To(Equal(fake.tags))
+		Expect(fake.requests).To(ContainElement("/v2/org/image/tags/list?n=2&last=1.0.0"))
+	})
+
+	It("only lists tags with the prefix", func() {
+		tags, err := sver.ListImageTags(repo, sver.WithTagPrefix("1."), fastRetry)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(tags).To(Equal([]string{"1.0.0", "1.1.0", "1.2.0"}))
+		Expect(fake.requests).To(ContainElement("/v2/org/image/tags/list?last=1."))
+	})
+
+	It("retries server errors", func() {
+		fake.failures = 2
+		fake.failStatus = http.StatusServiceUnavailable
+
+		tags, err := sver.ListImageTags(repo, fastRetry)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tags).To(HaveLen(len(fake.tags)))
+	})
+
+	It("honors Retry-After when rate limited", func() {
+		fake.failures = 1
+		fake.failStatus = http.StatusTooManyRequests
+		fake.retryAfter = "7"
+
+		waits := []time.Duration{}
+		sleep := sver.WithSleep(func(_ context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		})
+
+		_, err := sver.ListImageTags(repo, fastRetry, sleep)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(waits).To(Equal([]time.Duration{7 * time.Second}))
+	})
+
+	It("returns a rate limit error when the registry keeps rate limiting", func() {
+		fake.failures = 10
+		fake.failStatus = http.StatusTooManyRequests
+		fake.retryAfter = "3600"
+
+		_, err := sver.ListImageTags(repo, fastRetry)
+		Expect(err).To(HaveOccurred())
+
+		rlErr, ok := err.(*sver.RateLimitError)
+		Expect(ok).To(BeTrue())
+		Expect(rlErr.RetryAfter).To(Equal(time.Hour))
+	})
+
+	It("gives up after the maximum number of retries", func() {
+		fake.failures = 10
+		fake.failStatus = http.StatusBadGateway
+
+		_, err := sver.ListImageTags(repo, fastRetry)
+		Expect(err).To(HaveOccurred())
+		Expect(fake.failures).To(Equal(6))
+	})
+
+	It("logs registry requests", func() {
+		logged := []string{}
+		logf := func(format string, args ...interface{}) {
+			logged = append(logged, fmt.Sprintf(format, args...))
+		}
+
+		_, err := sver.ListImageTags(repo, sver.WithLogger(logf), fastRetry)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(logged).To(ContainElement(ContainSubstring("GET " + server.URL + "/v2/org/image/tags/list: 200 OK")))
+	})
+})
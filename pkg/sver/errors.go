@@ -0,0 +1,92 @@
+package sver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNoGit is returned when git isn't installed, or when the current
+	// directory isn't in a git work tree.
+	ErrNoGit = errors.New("git isn't available")
+	// ErrUnknownRef is returned when the ref to derive a version at doesn't
+	// name a commit of the repository.
+	ErrUnknownRef = errors.New("unknown ref")
+	// ErrNotOnTag is returned for a release version when HEAD isn't tagged.
+	ErrNotOnTag = errors.New("not on a tag, this is a pre release version")
+	// ErrDirty is returned for a release version when the tree has uncommitted
+	// changes.
+	ErrDirty = errors.New("version is dirty")
+	// ErrVersionExists is returned when a version that should be new already
+	// exists, like a minor version on a maintenance line.
+	ErrVersionExists = errors.New("version already exists")
+	// ErrModulePath is returned when the major version doesn't match the path
+	// of a Go module.
+	ErrModulePath = errors.New("major version doesn't match the module path")
+	// ErrRegistryAuth is returned when a registry rejects the credentials, or
+	// denies access to a repository.
+	ErrRegistryAuth = errors.New("registry authentication failed")
+)
+
+// ErrNotSemver is returned when a tag or a version isn't a supported semantic
+// version.
+type ErrNotSemver struct {
+	Tag string
+}
+
+func (e *ErrNotSemver) Error() string {
+	if strings.Contains(e.Tag, "+") {
+		return fmt.Sprintf("looks like your git tag '%s' has a semver with a + sign - that's not supported by this tool", e.Tag)
+	}
+
+	return fmt.Sprintf("'%s' doesn't seem to be a semantic version", e.Tag)
+}
+
+// kindError is an error with its own message, that matches a sentinel error
+// with errors.Is.
+type kindError struct {
+	kind  error
+	msg   string
+	cause error
+}
+
+func (e *kindError) Error() string {
+	if e.cause != nil {
+		return e.msg + ": " + e.cause.Error()
+	}
+
+	return e.msg
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *kindError) Unwrap() error {
+	return e.cause
+}
+
+// errorOf returns an error of a kind, like ErrDirty, with a specific message.
+func errorOf(kind error, format string, args ...interface{}) error {
+	return errors.WithStack(&kindError{kind: kind, msg: fmt.Sprintf(format, args...)})
+}
+
+// wrapKind wraps err with a message, as an error of a kind.
+func wrapKind(kind, err error, format string, args ...interface{}) error {
+	return errors.WithStack(&kindError{kind: kind, msg: fmt.Sprintf(format, args...), cause: err})
+}
+
+// wrapRegistryError wraps an error from a registry with a message, as an
+// ErrRegistryAuth error if the registry refused access.
+func wrapRegistryError(err error, format string, args ...interface{}) error {
+	var tErr *transport.Error
+	if errors.As(err, &tErr) && (tErr.StatusCode == http.StatusUnauthorized || tErr.StatusCode == http.StatusForbidden) {
+		return wrapKind(ErrRegistryAuth, err, format, args...)
+	}
+
+	return errors.Wrapf(err, format, args...)
+}
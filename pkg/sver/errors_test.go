@@ -0,0 +1,91 @@
+package sver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("errors", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "sver")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Chdir(dir)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("returns ErrNoGit outside of a work tree", func() {
+		_, err := sver.CurrentVersion(false, false)
+		Expect(errors.Is(err, sver.ErrNoGit)).To(BeTrue())
+	})
+
+	It("returns ErrNotOnTag for a release that isn't tagged", func() {
+		createGitDirWithTag("v1.0.0")
+		createCommit("second")
+
+		_, err := sver.CurrentVersion(true, false)
+		Expect(errors.Is(err, sver.ErrNotOnTag)).To(BeTrue())
+		Expect(err.Error()).To(Equal("not on a tag, this is a pre release version"))
+	})
+
+	It("returns ErrDirty for a dirty release", func() {
+		createGitDirWithTag("v1.0.0")
+		createUncomittedChanges()
+
+		_, err := sver.CurrentVersion(true, false)
+		Expect(errors.Is(err, sver.ErrDirty)).To(BeTrue())
+	})
+
+	It("returns ErrNotSemver with the tag", func() {
+		createGitDirWithTag("v1.0.0+gold")
+
+		_, err := sver.CurrentVersion(false, false)
+
+		var notSemver *sver.ErrNotSemver
+		Expect(errors.As(err, &notSemver)).To(BeTrue())
+		Expect(notSemver.Tag).To(Equal("v1.0.0+gold"))
+		Expect(err.Error()).To(ContainSubstring("has a semver with a + sign"))
+	})
+
+	It("returns ErrVersionExists when a minor version is taken", func() {
+		createGitDirWithTag("v1.0.0")
+		_, err := sver.Git("tag", "v1.1.0")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = sver.Next("1.0.0", "minor")
+		Expect(errors.Is(err, sver.ErrVersionExists)).To(BeTrue())
+	})
+
+	It("returns ErrModulePath when the major version doesn't match", func() {
+		err := sver.GoModule{Dir: ".", Path: "example.com/mod"}.CheckMajorVersion("2.0.0")
+		Expect(errors.Is(err, sver.ErrModulePath)).To(BeTrue())
+	})
+
+	It("returns ErrRegistryAuth when the registry refuses access", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v2/" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		_, err := sver.ListImageTags(strings.TrimPrefix(server.URL, "http://")+"/org/image", sver.WithRetry(0, 0))
+		Expect(errors.Is(err, sver.ErrRegistryAuth)).To(BeTrue())
+		Expect(errors.Is(err, sver.ErrDirty)).To(BeFalse())
+	})
+})
@@ -4,4 +4,8 @@ package sver
 var (
 	Git       = git
 	VerifyGit = verifyGit
+
+	RegistryAuthenticator = RegistryCredentials.authenticator
+
+	WithSleep = withSleep
 )
@@ -2,6 +2,7 @@ package sver
 
 import (
 	"bytes"
+	"context"
 	"os/exec"
 	"strings"
 
@@ -10,8 +11,23 @@ import (
 
 const gitBinary = "git"
 
-func git(args ...string) (string, error) {
-	cmd := exec.Command(gitBinary, args...)
+// GitBackend runs the git commands sver needs. ExecGit, the default, runs the
+// git binary; tests can use a fake instead.
+type GitBackend interface {
+	// Git runs git with args in dir, or in the current directory if dir is
+	// empty, and returns the output without surrounding whitespace.
+	Git(dir string, args ...string) (string, error)
+}
+
+// ExecGit is the GitBackend that runs the git binary found in the PATH.
+type ExecGit struct {
+	// Context kills git when it's done, if it's set.
+	Context context.Context
+}
+
+func (g ExecGit) Git(dir string, args ...string) (string, error) {
+	cmd := g.command(args...)
+	cmd.Dir = dir
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", errors.Wrapf(err, "unexpected result from git; output: \n%s\n", string(out))
@@ -20,19 +36,65 @@ func git(args ...string) (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
-func verifyGit() error {
+func (g ExecGit) command(args ...string) *exec.Cmd {
+	if g.Context != nil {
+		return exec.CommandContext(g.Context, gitBinary, args...)
+	}
+
+	return exec.Command(gitBinary, args...)
+}
+
+// repo is the git repository that sver works on: a directory, the current
+// one if empty, and the backend that runs git commands in it.
+type repo struct {
+	dir     string
+	backend GitBackend
+}
+
+func newRepo(dir string, backend GitBackend) repo {
+	if backend == nil {
+		backend = ExecGit{}
+	}
+
+	return repo{dir: dir, backend: backend}
+}
+
+func (r repo) git(args ...string) (string, error) {
+	return r.backend.Git(r.dir, args...)
+}
+
+func (r repo) verify() error {
+	execGit, ok := r.backend.(ExecGit)
+	if !ok {
+		if _, err := r.git("rev-parse", "--is-inside-work-tree"); err != nil {
+			return wrapKind(ErrNoGit, err, "could not determine if the directory is a git working tree")
+		}
+
+		return nil
+	}
+
 	_, err := exec.LookPath(gitBinary)
 	if err != nil {
-		return errors.New("git not found in your PATH; please install it")
+		return errorOf(ErrNoGit, "git not found in your PATH; please install it")
 	}
 
-	cmd := exec.Command(gitBinary, "rev-parse", "--is-inside-work-tree")
+	cmd := execGit.command("rev-parse", "--is-inside-work-tree")
+	cmd.Dir = r.dir
 	stdErrBuf := new(bytes.Buffer)
 	cmd.Stderr = stdErrBuf
 	err = cmd.Run()
 	if err != nil {
-		return errors.Wrapf(err, "could not determine if the current directory is a git working tree: %s", stdErrBuf.String())
+		return wrapKind(ErrNoGit, err, "could not determine if the current directory is a git working tree: %s", strings.TrimSpace(stdErrBuf.String()))
 	}
 
 	return nil
 }
+
+// git runs git in the current directory.
+func git(args ...string) (string, error) {
+	return newRepo("", nil).git(args...)
+}
+
+func verifyGit() error {
+	return newRepo("", nil).verify()
+}
@@ -0,0 +1,198 @@
+package sver
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// GoModule is a Go module in the repository. Nested modules are versioned with
+// tags prefixed with their directory, like `tools/v1.2.0`.
+type GoModule struct {
+	// Dir is the directory of the module, relative to the repository root.
+	Dir       string
+	Path      string
+	TagPrefix string
+}
+
+// GoModuleOptions are the options of FindGoModules and CheckGoModules.
+type GoModuleOptions struct {
+	// Dir is the directory of the repository. It defaults to the current
+	// directory.
+	Dir string
+	// Git runs the git commands. It defaults to ExecGit.
+	Git GitBackend
+}
+
+// FindGoModules returns the Go modules of the repository, the root module
+// first. The go.mod files are listed by git, so ignored files are skipped,
+// and so are the directories that the go command ignores, like `testdata` and
+// `vendor`.
+func FindGoModules(opts GoModuleOptions) ([]GoModule, error) {
+	r := newRepo(opts.Dir, opts.Git)
+	if err := r.verify(); err != nil {
+		return nil, err
+	}
+
+	root, err := r.git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, errors.Wrap(err, "exec error")
+	}
+
+	out, err := r.git("ls-files", "-z", "--cached", "--others", "--exclude-standard", "--full-name", "--",
+		":(top)go.mod", ":(top)*/go.mod")
+	if err != nil {
+		return nil, errors.Wrap(err, "exec error")
+	}
+
+	modules := []GoModule{}
+	seen := map[string]bool{}
+	for _, file := range strings.Split(out, "\x00") {
+		if file == "" || seen[file] || path.Base(file) != "go.mod" || ignoredGoModuleDir(path.Dir(file)) {
+			continue
+		}
+		seen[file] = true
+
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if os.IsNotExist(err) {
+			// The file is deleted in the working tree.
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to find Go modules")
+		}
+
+		modulePath := modfile.ModulePath(content)
+		if modulePath == "" {
+			return nil, errors.Errorf("no module path in [%s]", file)
+		}
+
+		dir := path.Dir(file)
+		tagPrefix := ""
+		if dir != "." {
+			tagPrefix = dir + "/"
+		}
+
+		modules = append(modules, GoModule{Dir: dir, Path: modulePath, TagPrefix: tagPrefix})
+	}
+
+	sort.SliceStable(modules, func(i, j int) bool {
+		return modules[i].Dir == "." && modules[j].Dir != "."
+	})
+
+	return modules, nil
+}
+
+// ignoredGoModuleDir tells if dir, relative to the repository root, is or is
+// inside a directory that the go command ignores, or a `node_modules`
+// directory.
+func ignoredGoModuleDir(dir string) bool {
+	if dir == "." {
+		return false
+	}
+
+	for _, name := range strings.Split(dir, "/") {
+		if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+			name == "testdata" || name == "vendor" || name == "node_modules" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CurrentTag returns the version of the latest tag of the module that's
+// reachable from HEAD, without the tag prefix, or an empty string if the module
+// has no tag yet.
+func (m GoModule) CurrentTag(opts GoModuleOptions) (string, error) {
+	tag, err := newRepo(opts.Dir, opts.Git).git("describe", "--tags", "--abbrev=0", "--match", m.TagPrefix+"v[0-9]*")
+	if err != nil {
+		if strings.Contains(err.Error(), "No names found") || strings.Contains(err.Error(), "cannot describe anything") {
+			return "", nil
+		}
+		return "", errors.Wrap(err, "exec error")
+	}
+
+	return strings.TrimPrefix(strings.TrimPrefix(tag, m.TagPrefix), "v"), nil
+}
+
+// CheckMajorVersion returns an error if the module path isn't compatible with
+// the major version of version. Versions 2 and above need a `/vN` suffix in the
+// module path, and versions 0 and 1 must not have one. The error suggests the
+// required change.
+func (m GoModule) CheckMajorVersion(version string) error {
+	major, _, _, _, err := Parts(version)
+	if err != nil {
+		return err
+	}
+
+	prefix, pathMajor, ok := module.SplitPathVersion(m.Path)
+	if !ok {
+		return errors.Errorf("module %s: invalid major version suffix in module path", m.Path)
+	}
+
+	if module.CheckPathMajor("v"+strings.TrimPrefix(version, "v"), pathMajor) == nil {
+		return nil
+	}
+
+	required := prefix
+	switch {
+	case strings.HasPrefix(pathMajor, "."):
+		required = fmt.Sprintf("%s.v%d", prefix, major)
+	case major >= 2:
+		required = fmt.Sprintf("%s/v%d", prefix, major)
+	}
+
+	if major >= 2 && pathMajor == "" {
+		return errorOf(ErrModulePath, "module %s: version %s needs the module path '%s'; change the module directive in %s and update the imports, or keep tagging v1 versions",
+			m.Path, version, required, m.goModFile())
+	}
+
+	return errorOf(ErrModulePath, "module %s: version %s doesn't match the '%s' suffix of the module path; tag a %s.x.y version, or change the module directive in %s to '%s' and update the imports",
+		m.Path, version, pathMajor, "v"+pathMajor[2:], m.goModFile(), required)
+}
+
+func (m GoModule) goModFile() string {
+	return filepath.ToSlash(filepath.Join(m.Dir, "go.mod"))
+}
+
+// CheckGoModules checks the major version of the Go modules of the repository.
+// The root module is checked against version. Nested modules are checked
+// against their latest tag, if they have one.
+func CheckGoModules(version string, opts GoModuleOptions) error {
+	modules, err := FindGoModules(opts)
+	if err != nil {
+		return err
+	}
+
+	problems := []string{}
+	for _, m := range modules {
+		moduleVersion := version
+		if m.TagPrefix != "" {
+			moduleVersion, err = m.CurrentTag(opts)
+			if err != nil {
+				return err
+			}
+			if moduleVersion == "" {
+				continue
+			}
+		}
+
+		if err := m.CheckMajorVersion(moduleVersion); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return errorOf(ErrModulePath, "%s", strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
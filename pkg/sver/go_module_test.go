@@ -0,0 +1,110 @@
+package sver_test
+
+import (
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("go modules", func() {
+	var (
+		repo *svertest.Repo
+		opts sver.GoModuleOptions
+	)
+
+	writeGoMod := func(moduleDir, modulePath string) {
+		repo.WriteFile(moduleDir+"/go.mod", "module "+modulePath+"\n\ngo 1.19\n")
+	}
+
+	BeforeEach(func() {
+		repo = svertest.NewRepo(GinkgoT()).Commit("initial").Tag("v1.0.0")
+		opts = sver.GoModuleOptions{Dir: repo.Dir}
+	})
+
+	AfterEach(func() {
+		repo.Close()
+	})
+
+	Context("checking the major version", func() {
+		It("accepts v0 and v1 without a suffix", func() {
+			m := sver.GoModule{Dir: ".", Path: "github.com/org/lib"}
+			Expect(m.CheckMajorVersion("0.3.0")).To(Succeed())
+			Expect(m.CheckMajorVersion("1.2.0-20221001120000.3.g0123abcd")).To(Succeed())
+		})
+
+		It("requires a suffix for v2 and above", func() {
+			m := sver.GoModule{Dir: ".", Path: "github.com/org/lib"}
+			err := m.CheckMajorVersion("2.0.0")
+			Expect(err).To(MatchError(ContainSubstring("needs the module path 'github.com/org/lib/v2'")))
+
+			Expect(sver.GoModule{Dir: ".", Path: "github.com/org/lib/v2"}.CheckMajorVersion("2.1.0")).To(Succeed())
+		})
+
+		It("rejects a suffix that doesn't match", func() {
+			m := sver.GoModule{Dir: "tools", Path: "github.com/org/lib/tools/v2"}
+
+			err := m.CheckMajorVersion("3.0.0")
+			Expect(err).To(MatchError(ContainSubstring("change the module directive in tools/go.mod to 'github.com/org/lib/tools/v3'")))
+
+			err = m.CheckMajorVersion("1.4.0")
+			Expect(err).To(MatchError(ContainSubstring("to 'github.com/org/lib/tools'")))
+		})
+
+		It("supports gopkg.in paths", func() {
+			m := sver.GoModule{Dir: ".", Path: "gopkg.in/lib.v2"}
+			Expect(m.CheckMajorVersion("2.3.0")).To(Succeed())
+			Expect(m.CheckMajorVersion("3.0.0")).To(MatchError(ContainSubstring("'gopkg.in/lib.v3'")))
+		})
+	})
+
+	Context("in a repository", func() {
+		BeforeEach(func() {
+			writeGoMod(".", "github.com/org/lib")
+			writeGoMod("tools", "github.com/org/lib/tools/v2")
+			writeGoMod("testdata/fixture", "example.com/fixture")
+			writeGoMod("web/node_modules/dep", "example.com/dep")
+		})
+
+		It("finds the root and nested modules", func() {
+			modules, err := sver.FindGoModules(opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(modules).To(Equal([]sver.GoModule{
+				{Dir: ".", Path: "github.com/org/lib", TagPrefix: ""},
+				{Dir: "tools", Path: "github.com/org/lib/tools/v2", TagPrefix: "tools/"},
+			}))
+		})
+
+		It("skips the go.mod files ignored by git", func() {
+			repo.WriteFile(".gitignore", "/generated/\n")
+			writeGoMod("generated", "example.com/generated")
+
+			modules, err := sver.FindGoModules(opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(modules).To(HaveLen(2))
+		})
+
+		It("checks the root module against the version", func() {
+			Expect(sver.CheckGoModules("1.1.0", opts)).To(Succeed())
+			Expect(sver.CheckGoModules("2.0.0", opts)).To(MatchError(ContainSubstring("module github.com/org/lib:")))
+		})
+
+		It("checks nested modules against their prefixed tags", func() {
+			repo.Tag("tools/v1.5.0")
+
+			tools := sver.GoModule{Dir: "tools", Path: "github.com/org/lib/tools/v2", TagPrefix: "tools/"}
+			version, err := tools.CurrentTag(opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(version).To(Equal("1.5.0"))
+
+			err = sver.CheckGoModules("1.1.0", opts)
+			Expect(err).To(MatchError(ContainSubstring("module github.com/org/lib/tools/v2: version 1.5.0")))
+			Expect(err).ToNot(MatchError(ContainSubstring("module github.com/org/lib:")))
+		})
+
+		It("skips nested modules without tags", func() {
+			Expect(sver.CheckGoModules("1.1.0", opts)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,351 @@
+package sver
+
+import (
+	"container/heap"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+)
+
+// HistoryOptions configure History.
+type HistoryOptions struct {
+	// Range selects the commits, like `v1.0.0..main`, or a ref for all of its
+	// history. It defaults to HEAD.
+	Range string
+	// FirstParent only keeps the commits of the first-parent chain, like the
+	// merges into a main branch.
+	FirstParent bool
+	// Date is the date of the commits, 'committer' or 'author', like the Date
+	// of Options. There's no SourceDateEpoch, since it's the date of a
+	// single commit.
+	Date string
+	// Dir is the directory of the repository. It defaults to the current
+	// directory.
+	Dir string
+	// Git runs the git commands. It defaults to ExecGit.
+	Git GitBackend
+}
+
+// HistoryEntry is the version sver derives at a commit.
+type HistoryEntry struct {
+	Commit string `json:"commit"`
+	// Date is the date of the commit chosen by the Date option, in RFC 3339
+	// format and UTC.
+	Date    string `json:"date"`
+	Version string `json:"version"`
+	// Tag is the release tag of the commit, or empty for a development
+	// version.
+	Tag string `json:"tag"`
+}
+
+// historyCommit is a commit of the graph, with what History derived for it.
+type historyCommit struct {
+	hash      string
+	short     string
+	time      int64
+	parents   []int
+	ancestors int
+	// closest is the closest tag reachable from the commit, or nil.
+	closest *historyTag
+	// tag is the tag of the commit, or nil.
+	tag *historyTag
+}
+
+type historyTag struct {
+	name      string
+	version   *semver.Version
+	annotated bool
+	ancestors int
+}
+
+// History returns the versions sver derives at each commit of a range, newest
+// first, like DeriveVersion with the commit as Ref and the dirty check
+// skipped and without SourceDateEpoch.
+//
+// The versions are derived in a single pass over the commit graph: the closest
+// tag of a commit is the reachable tag with the most ancestors, and its
+// distance is the difference in number of ancestors, which is what `git
+// describe` and `git rev-list --count` find. Tags that aren't semantic
+// versions, and tags of nested modules, are skipped, where DeriveVersion may
+// fail.
+func History(opts HistoryOptions) ([]HistoryEntry, error) {
+	r := newRepo(opts.Dir, opts.Git)
+	if err := r.verify(); err != nil {
+		return nil, errors.Wrap(err, "git error")
+	}
+
+	format, err := dateFormat(opts.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := opts.Range
+	if revisions == "" {
+		revisions = "HEAD"
+	}
+
+	selectArgs := []string{"rev-list"}
+	if opts.FirstParent {
+		selectArgs = append(selectArgs, "--first-parent")
+	}
+	selected, err := r.git(append(selectArgs, revisions, "--")...)
+	if err != nil {
+		return nil, wrapKind(ErrUnknownRef, err, "invalid range '%s'", revisions)
+	}
+
+	// The whole history of the range is needed to find the closest tags.
+	tips, err := r.git("rev-parse", "--revs-only", revisions)
+	if err != nil {
+		return nil, wrapKind(ErrUnknownRef, err, "invalid range '%s'", revisions)
+	}
+
+	logArgs := []string{"log", "--topo-order", "--reverse", "--abbrev=8", "--format=%H %h " + format + " %P"}
+	hasTip := false
+	for _, tip := range strings.Split(tips, "\n") {
+		if tip != "" && !strings.HasPrefix(tip, "^") {
+			logArgs = append(logArgs, tip)
+			hasTip = true
+		}
+	}
+	if !hasTip {
+		return []HistoryEntry{}, nil
+	}
+
+	graph, err := r.git(append(logArgs, "--")...)
+	if err != nil {
+		return nil, errors.Wrap(err, "exec error")
+	}
+
+	commits, err := parseHistoryGraph(graph)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := r.historyTags()
+	if err != nil {
+		return nil, err
+	}
+
+	walker := newAncestorWalker(len(commits))
+	for i := range commits {
+		c := &commits[i]
+
+		switch len(c.parents) {
+		case 0:
+			c.ancestors = 1
+		case 1:
+			c.ancestors = commits[c.parents[0]].ancestors + 1
+		default:
+			c.ancestors = commits[c.parents[0]].ancestors + walker.exclusive(commits, c.parents) + 1
+		}
+
+		for _, tag := range tags[c.hash] {
+			tag.ancestors = c.ancestors
+			if c.tag == nil || preferTag(tag, c.tag) {
+				c.tag = tag
+			}
+		}
+
+		c.closest = c.tag
+		if c.closest == nil {
+			for _, p := range c.parents {
+				closest := commits[p].closest
+				if closest != nil && (c.closest == nil || closest.ancestors > c.closest.ancestors ||
+					closest.ancestors == c.closest.ancestors && preferTag(closest, c.closest)) {
+					c.closest = closest
+				}
+			}
+		}
+	}
+
+	inRange := map[string]bool{}
+	for _, hash := range strings.Split(selected, "\n") {
+		inRange[hash] = true
+	}
+
+	entries := []HistoryEntry{}
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		if !inRange[c.hash] {
+			continue
+		}
+
+		date := time.Unix(c.time, 0).UTC()
+		entry := HistoryEntry{Commit: c.hash, Date: date.Format(time.RFC3339)}
+		switch {
+		case c.tag != nil:
+			entry.Tag = c.tag.name
+			entry.Version = c.tag.name
+		case c.closest != nil:
+			entry.Version = developmentVersion(c.closest.name, commitTimestamp(date), c.ancestors-c.closest.ancestors, "g"+c.short)
+		default:
+			entry.Version = developmentVersion("0.0.0", commitTimestamp(date), 0, "g"+c.short)
+		}
+		entry.Version = strings.TrimPrefix(entry.Version, "v")
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// parseHistoryGraph parses `git log --topo-order --reverse`, where parents come
+// before their children.
+func parseHistoryGraph(graph string) ([]historyCommit, error) {
+	commits := []historyCommit{}
+	index := map[string]int{}
+
+	for _, line := range strings.Split(graph, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		unixTime, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse git commit timestamp")
+		}
+
+		c := historyCommit{hash: fields[0], short: fields[1], time: unixTime}
+		for _, parent := range fields[3:] {
+			p, ok := index[parent]
+			if !ok {
+				// A shallow clone has no parents past its boundary.
+				continue
+			}
+			c.parents = append(c.parents, p)
+		}
+
+		index[c.hash] = len(commits)
+		commits = append(commits, c)
+	}
+
+	return commits, nil
+}
+
+// historyTags returns the semver tags of the repository by commit.
+func (r repo) historyTags() (map[string][]*historyTag, error) {
+	raw, err := r.readTags()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string][]*historyTag{}
+	for _, t := range raw {
+		if strings.Contains(t.name, "/") || !regexSupportedVersionFormat.MatchString(t.name) {
+			continue
+		}
+
+		v, err := semver.NewVersion(strings.TrimPrefix(t.name, "v"))
+		if err != nil {
+			continue
+		}
+
+		tags[t.commit] = append(tags[t.commit], &historyTag{name: t.name, version: v, annotated: t.annotated})
+	}
+
+	return tags, nil
+}
+
+// preferTag tells if a is preferred over b, for tags at the same distance:
+// annotated tags first, like `git describe`, then the highest version.
+func preferTag(a, b *historyTag) bool {
+	if a.annotated != b.annotated {
+		return a.annotated
+	}
+	if c := a.version.Compare(b.version); c != 0 {
+		return c > 0
+	}
+
+	return a.name < b.name
+}
+
+// ancestorWalker counts the ancestors of a merge that aren't ancestors of its
+// first parent, like `git rev-list --count P1..M`. Commits are walked from the
+// newest, by index in topological order, until only ancestors of the first
+// parent are left, so the walk stays within the merged branch.
+type ancestorWalker struct {
+	flags   []uint8
+	touched []int
+	queue   commitQueue
+}
+
+const (
+	fromMerged uint8 = 1 << iota
+	fromFirstParent
+)
+
+func newAncestorWalker(size int) *ancestorWalker {
+	return &ancestorWalker{flags: make([]uint8, size)}
+}
+
+func (w *ancestorWalker) exclusive(commits []historyCommit, parents []int) int {
+	defer w.reset()
+
+	// merged is the number of queued commits that aren't known to be ancestors
+	// of the first parent.
+	merged := 0
+	paint := func(c int, flag uint8) {
+		old := w.flags[c]
+		w.flags[c] |= flag
+		switch {
+		case old == 0:
+			w.touched = append(w.touched, c)
+			heap.Push(&w.queue, c)
+			if flag == fromMerged {
+				merged++
+			}
+		case old == fromMerged && flag&fromFirstParent != 0:
+			merged--
+		}
+	}
+
+	paint(parents[0], fromFirstParent)
+	for _, p := range parents[1:] {
+		paint(p, fromMerged)
+	}
+
+	// Children have higher indexes than their parents, so a commit is popped
+	// after all of its children and its flags are final.
+	count := 0
+	for merged > 0 {
+		c := heap.Pop(&w.queue).(int)
+		flag := w.flags[c]
+		if flag == fromMerged {
+			count++
+			merged--
+		}
+		for _, p := range commits[c].parents {
+			paint(p, flag)
+		}
+	}
+
+	return count
+}
+
+func (w *ancestorWalker) reset() {
+	for _, c := range w.touched {
+		w.flags[c] = 0
+	}
+	w.touched = w.touched[:0]
+	w.queue = w.queue[:0]
+}
+
+// commitQueue is a max-heap of commit indexes.
+type commitQueue []int
+
+func (q commitQueue) Len() int            { return len(q) }
+func (q commitQueue) Less(i, j int) bool  { return q[i] > q[j] }
+func (q commitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(int)) }
+
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return x
+}
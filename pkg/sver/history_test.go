@@ -0,0 +1,89 @@
+package sver_test
+
+import (
+	"strings"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("history", func() {
+	var repo *svertest.Repo
+
+	BeforeEach(func() {
+		repo = svertest.NewRepo(GinkgoT()).
+			Commit("initial").
+			Commit("feature").Tag("v1.0.0").
+			Branch("release-1.0").Commit("fix").Tag("v1.0.1").Commit("fix").
+			Checkout("main").Commit("feature").
+			Branch("topic").Commit("topic 1").Commit("topic 2").
+			Checkout("main").Commit("feature").AnnotatedTag("v1.1.0", "release 1.1.0").
+			Merge("release-1.0").Commit("feature").Merge("topic").
+			Branch("other").Commit("other").Tag("v1.2.0-rc.1").
+			Checkout("main").Commit("feature").Merge("other").Commit("feature")
+	})
+
+	AfterEach(func() {
+		repo.Close()
+	})
+
+	It("derives the same version as at each commit", func() {
+		entries, err := sver.History(sver.HistoryOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+
+		commits := strings.Split(repo.Git("rev-list", "--topo-order", "HEAD"), "\n")
+		Expect(entries).To(HaveLen(len(commits)))
+
+		for i, entry := range entries {
+			Expect(entry.Commit).To(Equal(commits[i]))
+
+			d, err := sver.DeriveVersion(sver.Options{Dir: repo.Dir, Ref: entry.Commit})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entry.Version).To(Equal(d.Version), "version of %s", entry.Commit)
+		}
+	})
+
+	It("returns the tags of the releases", func() {
+		entries, err := sver.History(sver.HistoryOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+
+		tags := []string{}
+		for _, entry := range entries {
+			if entry.Tag != "" {
+				Expect(entry.Version).To(Equal(strings.TrimPrefix(entry.Tag, "v")))
+				tags = append(tags, entry.Tag)
+			}
+		}
+		Expect(tags).To(ConsistOf("v1.0.0", "v1.0.1", "v1.1.0", "v1.2.0-rc.1"))
+	})
+
+	It("only returns the commits of a range", func() {
+		entries, err := sver.History(sver.HistoryOptions{Dir: repo.Dir, Range: "v1.1.0..main", FirstParent: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(entries).To(HaveLen(6))
+		Expect(entries[0].Commit).To(Equal(repo.Head()))
+		Expect(entries[0].Version).To(HavePrefix("1.2.0-rc.1-"))
+		Expect(entries[0].Date).To(HaveSuffix("Z"))
+	})
+
+	It("starts at 0.0.0 without tags", func() {
+		empty := svertest.NewRepo(GinkgoT()).Commit("initial").Commit("feature")
+		defer empty.Close()
+
+		entries, err := sver.History(sver.HistoryOptions{Dir: empty.Dir})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Version).To(MatchRegexp(`^0\.0\.0-[0-9]{14}\.0\.g[0-9a-f]{8}$`))
+	})
+
+	It("fails for an invalid range", func() {
+		_, err := sver.History(sver.HistoryOptions{Dir: repo.Dir, Range: "v9.9.9..main"})
+		Expect(errors.Is(err, sver.ErrUnknownRef)).To(BeTrue())
+	})
+})
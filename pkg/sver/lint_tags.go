@@ -0,0 +1,373 @@
+package sver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+)
+
+// TagProblemKind is the kind of problem found by LintTags.
+type TagProblemKind string
+
+const (
+	// InvalidTag is a tag that isn't a supported semantic version.
+	InvalidTag TagProblemKind = "invalid"
+	// DuplicateTag is a version that's tagged both with and without the `v`
+	// prefix.
+	DuplicateTag TagProblemKind = "duplicate"
+	// RegressionTag is a tag that's lower than a tag of one of its ancestors.
+	RegressionTag TagProblemKind = "regression"
+	// GapTag is a release that skips versions, like `1.2.5` after `1.2.3`.
+	GapTag TagProblemKind = "gap"
+)
+
+// TagProblem is a problem with one or more tags.
+type TagProblem struct {
+	Kind    TagProblemKind `json:"kind"`
+	Tags    []string       `json:"tags"`
+	Message string         `json:"message"`
+}
+
+func (p TagProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Kind, p.Message)
+}
+
+// LintOptions are the options of LintTags and RefTagProblems.
+type LintOptions struct {
+	// Prefix only checks the tags that start with it, like `tools/` for the
+	// tags of a nested module. Without a prefix, tags that contain a `/` are
+	// skipped.
+	Prefix string
+	// Ref is the commit whose tags are checked by RefTagProblems. It defaults
+	// to HEAD.
+	Ref string
+	// Dir is the directory of the repository. It defaults to the current
+	// directory.
+	Dir string
+	// Git runs the git commands. It defaults to ExecGit.
+	Git GitBackend
+}
+
+type lintedTag struct {
+	rawTag
+	version *semver.Version
+}
+
+// LintTags checks the tags of the repository that start with the prefix of
+// the options.
+func LintTags(opts LintOptions) ([]TagProblem, error) {
+	r := newRepo(opts.Dir, opts.Git)
+	if err := r.verify(); err != nil {
+		return nil, errors.Wrap(err, "git error")
+	}
+
+	tags, invalid, err := r.lintedTags(opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []TagProblem{}
+	for _, t := range invalid {
+		problems = append(problems, invalidTagProblem(t))
+	}
+	problems = append(problems, duplicateTags(tags)...)
+	problems = append(problems, gapTags(tags)...)
+
+	tips := []string{}
+	for _, t := range tags {
+		tips = append(tips, t.commit)
+	}
+
+	regressions, err := r.regressionTags(tags, tips)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(problems, regressions...), nil
+}
+
+// lintedTags returns the semver tags that start with prefix, sorted by version,
+// and the tags that aren't semantic versions.
+func (r repo) lintedTags(prefix string) ([]lintedTag, []rawTag, error) {
+	raw, err := r.readTags()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invalid := []rawTag{}
+	tags := []lintedTag{}
+	for _, t := range raw {
+		if !strings.HasPrefix(t.name, prefix) || (prefix == "" && strings.Contains(t.name, "/")) {
+			continue
+		}
+
+		version := strings.TrimPrefix(t.name, prefix)
+		if !regexSupportedVersionFormat.MatchString(version) {
+			invalid = append(invalid, t)
+			continue
+		}
+
+		v, err := semver.NewVersion(strings.TrimPrefix(version, "v"))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tags = append(tags, lintedTag{rawTag: t, version: v})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		if c := tags[i].version.Compare(tags[j].version); c != 0 {
+			return c < 0
+		}
+		return tags[i].name < tags[j].name
+	})
+
+	return tags, invalid, nil
+}
+
+func invalidTagProblem(t rawTag) TagProblem {
+	return TagProblem{
+		Kind:    InvalidTag,
+		Tags:    []string{t.name},
+		Message: fmt.Sprintf("'%s' isn't a semantic version", t.name),
+	}
+}
+
+// duplicateTags finds versions that are tagged more than once, like `v1.2.0`
+// and `1.2.0`.
+func duplicateTags(tags []lintedTag) []TagProblem {
+	problems := []TagProblem{}
+	for i := 0; i < len(tags); {
+		j := i + 1
+		for j < len(tags) && tags[j].version.Equal(tags[i].version) {
+			j++
+		}
+
+		if j-i > 1 {
+			names := []string{}
+			for _, t := range tags[i:j] {
+				names = append(names, t.name)
+			}
+			problems = append(problems, TagProblem{
+				Kind:    DuplicateTag,
+				Tags:    names,
+				Message: fmt.Sprintf("%s are the same version", strings.Join(names, " and ")),
+			})
+		}
+
+		i = j
+	}
+
+	return problems
+}
+
+// gapTags finds releases that skip versions. A new minor version must start at
+// patch 0, and a new major version at `X.0.0`.
+func gapTags(tags []lintedTag) []TagProblem {
+	problems := []TagProblem{}
+
+	var previous *lintedTag
+	for i := range tags {
+		current := &tags[i]
+		if !isRelease(current.version) {
+			continue
+		}
+
+		if previous != nil && previous.version.Equal(current.version) {
+			continue
+		}
+
+		expected := ""
+		v := current.version
+		switch {
+		case previous == nil:
+		case v.Major() == previous.version.Major() && v.Minor() == previous.version.Minor():
+			if v.Patch() != previous.version.Patch()+1 {
+				expected = fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), previous.version.Patch()+1)
+			}
+		case v.Major() == previous.version.Major():
+			if v.Minor() != previous.version.Minor()+1 || v.Patch() != 0 {
+				expected = fmt.Sprintf("%d.%d.0", v.Major(), previous.version.Minor()+1)
+			}
+		default:
+			if v.Major() != previous.version.Major()+1 || v.Minor() != 0 || v.Patch() != 0 {
+				expected = fmt.Sprintf("%d.0.0", previous.version.Major()+1)
+			}
+		}
+
+		if expected != "" {
+			problems = append(problems, TagProblem{
+				Kind:    GapTag,
+				Tags:    []string{current.name},
+				Message: fmt.Sprintf("%s follows %s; expected %s", current.name, previous.name, expected),
+			})
+		}
+
+		previous = current
+	}
+
+	return problems
+}
+
+// regressionTags finds the tags of the tips that are lower than the highest
+// tag of one of the ancestors of their commit. The history of the tips is read
+// in a single pass, in topological order, where the highest tag reachable from
+// a commit is the highest of its own tags and of those of its parents.
+func (r repo) regressionTags(tags []lintedTag, tips []string) ([]TagProblem, error) {
+	if len(tips) == 0 {
+		return []TagProblem{}, nil
+	}
+
+	byCommit := map[string][]*lintedTag{}
+	for i := range tags {
+		byCommit[tags[i].commit] = append(byCommit[tags[i].commit], &tags[i])
+	}
+
+	out, err := r.git(append([]string{"rev-list", "--topo-order", "--reverse", "--parents"}, uniqueStrings(tips)...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "exec error")
+	}
+
+	isTip := map[string]bool{}
+	for _, tip := range tips {
+		isTip[tip] = true
+	}
+
+	// highest is the highest tag that points to a commit or to one of its
+	// ancestors.
+	highest := map[string]*lintedTag{}
+	regressions := map[string]TagProblem{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		commit := fields[0]
+
+		var inherited *lintedTag
+		for _, parent := range fields[1:] {
+			// A shallow clone has no parents past its boundary.
+			if tag := highest[parent]; tag != nil && (inherited == nil || higherTag(tag, inherited)) {
+				inherited = tag
+			}
+		}
+
+		top := inherited
+		for _, current := range byCommit[commit] {
+			if isTip[commit] && inherited != nil && current.version.LessThan(inherited.version) {
+				regressions[current.name] = TagProblem{
+					Kind:    RegressionTag,
+					Tags:    []string{current.name},
+					Message: fmt.Sprintf("%s is lower than %s, which is tagged on an ancestor commit", current.name, inherited.name),
+				}
+			}
+
+			if top == nil || higherTag(current, top) {
+				top = current
+			}
+		}
+
+		if top != nil {
+			highest[commit] = top
+		}
+	}
+
+	problems := []TagProblem{}
+	for _, t := range tags {
+		if problem, ok := regressions[t.name]; ok {
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems, nil
+}
+
+// higherTag tells if a is a higher version than b, or the same version with a
+// name sorted first.
+func higherTag(a, b *lintedTag) bool {
+	if c := a.version.Compare(b.version); c != 0 {
+		return c > 0
+	}
+
+	return a.name < b.name
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+
+	return unique
+}
+
+// RefTagProblems returns the problems of LintTags that involve a tag pointing
+// to the ref of the options. Only the tags of the ref are checked, against the
+// other tags, so it's cheaper than LintTags.
+func RefTagProblems(opts LintOptions) ([]TagProblem, error) {
+	r := newRepo(opts.Dir, opts.Git)
+	if err := r.verify(); err != nil {
+		return nil, errors.Wrap(err, "git error")
+	}
+
+	ref := opts.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	rev, err := r.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := r.git("rev-parse", rev)
+	if err != nil {
+		return nil, errors.Wrap(err, "exec error")
+	}
+
+	tags, invalid, err := r.lintedTags(opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	atRef := map[string]bool{}
+	for _, t := range tags {
+		if t.commit == commit {
+			atRef[t.name] = true
+		}
+	}
+
+	problems := []TagProblem{}
+	for _, t := range invalid {
+		if t.commit == commit {
+			problems = append(problems, invalidTagProblem(t))
+		}
+	}
+
+	for _, problem := range append(duplicateTags(tags), gapTags(tags)...) {
+		for _, name := range problem.Tags {
+			if atRef[name] {
+				problems = append(problems, problem)
+				break
+			}
+		}
+	}
+
+	if len(atRef) == 0 {
+		return problems, nil
+	}
+
+	regressions, err := r.regressionTags(tags, []string{commit})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(problems, regressions...), nil
+}
@@ -0,0 +1,163 @@
+package sver_test
+
+import (
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("linting tags", func() {
+	var repo *svertest.Repo
+
+	tag := func(names ...string) {
+		for _, name := range names {
+			repo.Tag(name)
+		}
+	}
+
+	BeforeEach(func() {
+		repo = svertest.NewRepo(GinkgoT())
+	})
+
+	AfterEach(func() {
+		repo.Close()
+	})
+
+	It("accepts consecutive versions", func() {
+		repo.Commit("a")
+		tag("v0.1.0", "tools/v9.9.9")
+		repo.Commit("b")
+		tag("v0.1.1", "v0.2.0-rc.1")
+		repo.Commit("c")
+		tag("v0.2.0", "v1.0.0")
+		repo.Commit("d")
+		tag("v1.1.0")
+
+		problems, err := sver.LintTags(sver.LintOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(BeEmpty())
+	})
+
+	It("reports invalid tags and duplicates", func() {
+		repo.Commit("a")
+		tag("v1.0.0", "1.0.0", "latest", "v1.0.0+build")
+
+		problems, err := sver.LintTags(sver.LintOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(ConsistOf(
+			sver.TagProblem{Kind: sver.InvalidTag, Tags: []string{"latest"}, Message: "'latest' isn't a semantic version"},
+			sver.TagProblem{Kind: sver.InvalidTag, Tags: []string{"v1.0.0+build"}, Message: "'v1.0.0+build' isn't a semantic version"},
+			sver.TagProblem{Kind: sver.DuplicateTag, Tags: []string{"1.0.0", "v1.0.0"}, Message: "1.0.0 and v1.0.0 are the same version"},
+		))
+	})
+
+	It("reports skipped versions", func() {
+		repo.Commit("a")
+		tag("v1.2.3")
+		repo.Commit("b")
+		tag("v1.2.5")
+		repo.Commit("c")
+		tag("v1.4.0")
+		repo.Commit("d")
+		tag("v3.0.0")
+
+		problems, err := sver.LintTags(sver.LintOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(Equal([]sver.TagProblem{
+			{Kind: sver.GapTag, Tags: []string{"v1.2.5"}, Message: "v1.2.5 follows v1.2.3; expected 1.2.4"},
+			{Kind: sver.GapTag, Tags: []string{"v1.4.0"}, Message: "v1.4.0 follows v1.2.5; expected 1.3.0"},
+			{Kind: sver.GapTag, Tags: []string{"v3.0.0"}, Message: "v3.0.0 follows v1.4.0; expected 2.0.0"},
+		}))
+	})
+
+	It("reports regressions along history", func() {
+		repo.Commit("a")
+		tag("v1.10.0")
+		repo.Commit("b")
+		tag("v1.11.0")
+		repo.Commit("c")
+		tag("v1.9.0")
+
+		problems, err := sver.LintTags(sver.LintOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(ContainElement(sver.TagProblem{
+			Kind:    sver.RegressionTag,
+			Tags:    []string{"v1.9.0"},
+			Message: "v1.9.0 is lower than v1.11.0, which is tagged on an ancestor commit",
+		}))
+	})
+
+	It("reports regressions through merges", func() {
+		repo.Commit("a")
+		tag("v1.0.0")
+		repo.Branch("maintenance")
+		repo.Commit("b")
+		tag("v2.0.0")
+		repo.Checkout("main")
+		repo.Commit("c")
+		repo.Merge("maintenance")
+		tag("v1.1.0")
+
+		problems, err := sver.LintTags(sver.LintOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(ContainElement(sver.TagProblem{
+			Kind:    sver.RegressionTag,
+			Tags:    []string{"v1.1.0"},
+			Message: "v1.1.0 is lower than v2.0.0, which is tagged on an ancestor commit",
+		}))
+	})
+
+	It("checks prefixed tags", func() {
+		repo.Commit("a")
+		tag("tools/v1.0.0", "tools/v1.0.2", "tools/next")
+
+		problems, err := sver.LintTags(sver.LintOptions{Prefix: "tools/", Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(HaveLen(2))
+	})
+
+	It("only returns the problems of the tags of HEAD", func() {
+		repo.Commit("a")
+		tag("v1.0.0", "v1.0.2")
+		repo.Commit("b")
+		tag("v1.0.3")
+
+		problems, err := sver.RefTagProblems(sver.LintOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(BeEmpty())
+
+		repo.Commit("c")
+		tag("v1.0.5")
+
+		problems, err = sver.RefTagProblems(sver.LintOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].Tags).To(Equal([]string{"v1.0.5"}))
+	})
+
+	It("only checks the tags of the ref", func() {
+		repo.Commit("a")
+		tag("v1.1.0")
+		repo.Commit("b")
+		tag("v1.0.0", "latest")
+		repo.Commit("c")
+		tag("v1.2.0")
+
+		problems, err := sver.RefTagProblems(sver.LintOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(BeEmpty())
+
+		problems, err = sver.RefTagProblems(sver.LintOptions{Ref: "v1.0.0", Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(ConsistOf(
+			sver.TagProblem{Kind: sver.InvalidTag, Tags: []string{"latest"}, Message: "'latest' isn't a semantic version"},
+			sver.TagProblem{
+				Kind:    sver.RegressionTag,
+				Tags:    []string{"v1.0.0"},
+				Message: "v1.0.0 is lower than v1.1.0, which is tagged on an ancestor commit",
+			},
+		))
+	})
+})
@@ -0,0 +1,214 @@
+package sver
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+)
+
+// Tag is a semver tag of the repository.
+type Tag struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	// Date is the date of the commit, in RFC 3339 format and UTC.
+	Date      string `json:"date"`
+	Reachable bool   `json:"reachable"`
+}
+
+// ListOptions filters the tags returned by ListTags.
+type ListOptions struct {
+	// Prefix only keeps tags that start with it, like `tools/` for the tags of
+	// a nested module. It's removed from the versions.
+	Prefix string
+	// Since only keeps versions greater than or equal to it.
+	Since string
+	// Constraint only keeps versions that match it.
+	Constraint *Constraint
+	// IncludePreReleases also keeps pre-releases whose release matches
+	// Constraint.
+	IncludePreReleases bool
+	// LatestPer only keeps the latest version of each 'major' or 'minor'
+	// series.
+	LatestPer string
+	// Dir is the directory of the repository. It defaults to the current
+	// directory.
+	Dir string
+	// Git runs the git commands. It defaults to ExecGit.
+	Git GitBackend
+}
+
+const tagFieldSeparator = "\x1f"
+
+// ListTags returns the semver tags of the repository, sorted by precedence.
+// Tags that aren't semantic versions are skipped.
+func ListTags(opts ListOptions) ([]Tag, error) {
+	r := newRepo(opts.Dir, opts.Git)
+	if err := r.verify(); err != nil {
+		return nil, errors.Wrap(err, "git error")
+	}
+
+	if opts.LatestPer != "" && opts.LatestPer != "major" && opts.LatestPer != "minor" {
+		return nil, errors.Errorf("invalid value '%s' for latest per; supported values are 'major' and 'minor'", opts.LatestPer)
+	}
+
+	var since *semver.Version
+	if opts.Since != "" {
+		var err error
+		since, err = semver.NewVersion(opts.Since)
+		if err != nil {
+			return nil, errors.WithStack(&ErrNotSemver{Tag: opts.Since})
+		}
+	}
+
+	raw, err := r.readTags()
+	if err != nil {
+		return nil, err
+	}
+
+	reachable, err := r.reachableTags("HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []Tag{}
+	versions := map[string]*semver.Version{}
+	for _, t := range raw {
+		name := t.name
+		version := strings.TrimPrefix(name, opts.Prefix)
+		if !strings.HasPrefix(name, opts.Prefix) || !regexSupportedVersionFormat.MatchString(version) {
+			continue
+		}
+		version = strings.TrimPrefix(version, "v")
+
+		v, err := semver.NewVersion(version)
+		if err != nil {
+			continue
+		}
+
+		if since != nil && v.LessThan(since) {
+			continue
+		}
+
+		if opts.Constraint != nil {
+			ok, err := opts.Constraint.Check(version, opts.IncludePreReleases)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		tags = append(tags, Tag{
+			Name:      name,
+			Version:   version,
+			Commit:    t.commit,
+			Date:      t.date,
+			Reachable: reachable[name],
+		})
+		versions[name] = v
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		if c := versions[tags[i].Name].Compare(versions[tags[j].Name]); c != 0 {
+			return c < 0
+		}
+		return tags[i].Name < tags[j].Name
+	})
+
+	if opts.LatestPer != "" {
+		tags = latestPer(tags, versions, opts.LatestPer)
+	}
+
+	return tags, nil
+}
+
+// rawTag is a tag of the repository, that may not be a semantic version.
+type rawTag struct {
+	name      string
+	commit    string
+	date      string
+	annotated bool
+}
+
+// readTags returns all tags of the repository, with their commit and the date
+// of the commit in UTC.
+func (r repo) readTags() ([]rawTag, error) {
+	format := strings.Join([]string{
+		"%(refname:strip=2)",
+		"%(objectname)",
+		"%(*objectname)",
+		"%(committerdate:iso-strict)",
+		"%(*committerdate:iso-strict)",
+	}, tagFieldSeparator)
+
+	out, err := r.git("for-each-ref", "--format="+format, "refs/tags")
+	if err != nil {
+		return nil, errors.Wrap(err, "exec error")
+	}
+
+	tags := []rawTag{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, tagFieldSeparator)
+		if len(fields) != 5 {
+			continue
+		}
+
+		// Annotated tags point to a tag object, which points to the commit.
+		commit, date := fields[1], fields[3]
+		if fields[2] != "" {
+			commit, date = fields[2], fields[4]
+		}
+
+		if parsed, err := time.Parse(time.RFC3339, date); err == nil {
+			date = parsed.UTC().Format(time.RFC3339)
+		}
+
+		tags = append(tags, rawTag{name: fields[0], commit: commit, date: date, annotated: fields[2] != ""})
+	}
+
+	return tags, nil
+}
+
+// reachableTags returns the tags that point to rev or to one of its ancestors.
+func (r repo) reachableTags(rev string) (map[string]bool, error) {
+	reachable := map[string]bool{}
+
+	if _, err := r.git("rev-parse", "--verify", "--quiet", rev); err != nil {
+		// No commit yet.
+		return reachable, nil
+	}
+
+	out, err := r.git("tag", "--merged", rev)
+	if err != nil {
+		return nil, errors.Wrap(err, "exec error")
+	}
+
+	for _, name := range strings.Split(out, "\n") {
+		if name != "" {
+			reachable[name] = true
+		}
+	}
+
+	return reachable, nil
+}
+
+// latestPer keeps the last tag of each series of sorted tags.
+func latestPer(tags []Tag, versions map[string]*semver.Version, series string) []Tag {
+	latest := []Tag{}
+	for i, tag := range tags {
+		if i+1 < len(tags) {
+			v, next := versions[tag.Name], versions[tags[i+1].Name]
+			if v.Major() == next.Major() && (series == "major" || v.Minor() == next.Minor()) {
+				continue
+			}
+		}
+		latest = append(latest, tag)
+	}
+
+	return latest
+}
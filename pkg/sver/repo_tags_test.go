@@ -0,0 +1,124 @@
+package sver_test
+
+import (
+	"os"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("listing tags", func() {
+	var dir string
+
+	tag := func(name string, annotated bool) {
+		args := []string{"tag", name}
+		if annotated {
+			args = []string{"tag", "--annotate", "--message", name, name}
+		}
+		_, err := git(args...)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	versions := func(tags []sver.Tag) []string {
+		result := []string{}
+		for _, t := range tags {
+			result = append(result, t.Version)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "sver-list")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Chdir(dir)).To(Succeed())
+
+		_, err = git("init")
+		Expect(err).ToNot(HaveOccurred())
+
+		createCommit("a")
+		tag("v1.0.0", false)
+		tag("v1.10.0", true)
+		tag("1.2.0", false)
+		tag("tools/v0.1.0", false)
+		tag("not-a-version", false)
+
+		createCommit("b")
+		tag("v1.2.1", false)
+		tag("v2.0.0-rc.1", false)
+
+		_, err = git("checkout", "--quiet", "-b", "other", "HEAD~1")
+		Expect(err).ToNot(HaveOccurred())
+		createCommit("c")
+		tag("v2.0.0", false)
+		_, err = git("checkout", "--quiet", "-")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("sorts semver tags by precedence", func() {
+		tags, err := sver.ListTags(sver.ListOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions(tags)).To(Equal([]string{"1.0.0", "1.2.0", "1.2.1", "1.10.0", "2.0.0-rc.1", "2.0.0"}))
+	})
+
+	It("returns the commit, date and reachability", func() {
+		head, err := git("rev-parse", "HEAD")
+		Expect(err).ToNot(HaveOccurred())
+		first, err := git("rev-parse", "HEAD~1")
+		Expect(err).ToNot(HaveOccurred())
+
+		tags, err := sver.ListTags(sver.ListOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(tags[2].Name).To(Equal("v1.2.1"))
+		Expect(tags[2].Commit).To(Equal(head))
+		Expect(tags[2].Reachable).To(BeTrue())
+		Expect(tags[2].Date).To(MatchRegexp(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`))
+
+		// Annotated tags resolve to their commit.
+		Expect(tags[3].Name).To(Equal("v1.10.0"))
+		Expect(tags[3].Commit).To(Equal(first))
+
+		Expect(tags[5].Name).To(Equal("v2.0.0"))
+		Expect(tags[5].Reachable).To(BeFalse())
+	})
+
+	It("filters by prefix", func() {
+		tags, err := sver.ListTags(sver.ListOptions{Prefix: "tools/"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tags).To(HaveLen(1))
+		Expect(tags[0].Name).To(Equal("tools/v0.1.0"))
+		Expect(tags[0].Version).To(Equal("0.1.0"))
+	})
+
+	It("filters by version", func() {
+		tags, err := sver.ListTags(sver.ListOptions{Since: "1.2.1"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions(tags)).To(Equal([]string{"1.2.1", "1.10.0", "2.0.0-rc.1", "2.0.0"}))
+
+		constraint, err := sver.ParseConstraint("^1.2")
+		Expect(err).ToNot(HaveOccurred())
+		tags, err = sver.ListTags(sver.ListOptions{Constraint: constraint})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions(tags)).To(Equal([]string{"1.2.0", "1.2.1", "1.10.0"}))
+	})
+
+	It("keeps the latest version per series", func() {
+		tags, err := sver.ListTags(sver.ListOptions{LatestPer: "minor"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions(tags)).To(Equal([]string{"1.0.0", "1.2.1", "1.10.0", "2.0.0"}))
+
+		tags, err = sver.ListTags(sver.ListOptions{LatestPer: "major"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions(tags)).To(Equal([]string{"1.10.0", "2.0.0"}))
+
+		_, err = sver.ListTags(sver.ListOptions{LatestPer: "patch"})
+		Expect(err).To(HaveOccurred())
+	})
+})
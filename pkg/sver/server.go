@@ -0,0 +1,302 @@
+package sver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ServerOptions configure the HTTP API returned by NewServer.
+type ServerOptions struct {
+	// Repos maps the repository names used in URLs, like `org/app`, to the
+	// directories of local clones or mirrors.
+	Repos map[string]string
+	// Destinations returns the registries that hold the existing tags of an
+	// image. The tags endpoint isn't available without it.
+	Destinations func(image string) ([]Destination, error)
+	// TagOptions returns the tag options of an image, like its tag policy. The
+	// channels, edge and variant query parameters override them.
+	TagOptions func(image string) TagOptions
+	// RegistryOptions configure how registries are queried.
+	RegistryOptions []RegistryOption
+	// Timeout limits how long a request can take. Zero means no limit.
+	Timeout time.Duration
+}
+
+type server struct {
+	opts ServerOptions
+}
+
+// httpError is an error with the status of the response.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, err: errors.Errorf(format, args...)}
+}
+
+// tagsResponse is the response of the tags endpoint.
+type tagsResponse struct {
+	Version string `json:"version"`
+	TagsDerivation
+	Drift []VersionDrift `json:"drift"`
+}
+
+// NewServer returns an HTTP API that answers what sver would say for a
+// repository. All endpoints accept GET requests and return JSON:
+//
+//	/healthz                            {"status": "ok"}
+//	/v1/repos                           the repository names
+//	/v1/repos/<name>/version            the current version, as a Derivation
+//	/v1/repos/<name>/next?type=minor    the next version, as a NextDerivation
+//	/v1/repos/<name>/tags?image=org/app the tags to push for the current version
+//	/v1/repos/<name>/list               the semver tags of the repository
+//
+// The version, next and tags endpoints accept the `ref`, `date`, `release` and
+// `force` query parameters, like the flags of the same name. The list endpoint accepts
+// `prefix`, `since`, `constraint`, `include_pre_releases` and `latest_per`.
+// Errors are returned as `{"error": "..."}`.
+func NewServer(opts ServerOptions) http.Handler {
+	s := &server{opts: opts}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handle(func(*http.Request) (interface{}, error) {
+		return map[string]string{"status": "ok"}, nil
+	}))
+	mux.HandleFunc("/v1/repos", s.handle(func(*http.Request) (interface{}, error) {
+		names := []string{}
+		for name := range s.opts.Repos {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return names, nil
+	}))
+	mux.HandleFunc("/v1/repos/", s.handle(s.repo))
+
+	return mux
+}
+
+func (s *server) handle(handler func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		if s.opts.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), s.opts.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+
+		result, err := handler(r)
+		if err != nil {
+			status := errorStatus(err)
+			if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+				// git was killed, which doesn't say why.
+				status = http.StatusGatewayTimeout
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func (s *server) repo(r *http.Request) (interface{}, error) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/repos/"), "/")
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return nil, &httpError{status: http.StatusNotFound, err: errors.New("not found")}
+	}
+
+	name, endpoint := path[:i], path[i+1:]
+	dir, ok := s.opts.Repos[name]
+	if !ok {
+		return nil, &httpError{status: http.StatusNotFound, err: errors.Errorf("unknown repository '%s'", name)}
+	}
+
+	query := r.URL.Query()
+	git := ExecGit{Context: r.Context()}
+
+	if endpoint == "list" {
+		return s.list(query, ListOptions{Dir: dir, Git: git})
+	}
+
+	release, err := boolParam(query.Get("release"))
+	if err != nil {
+		return nil, err
+	}
+
+	force, err := boolParam(query.Get("force"))
+	if err != nil {
+		return nil, err
+	}
+
+	explain, err := boolParam(query.Get("explain"))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := Options{Dir: dir, Git: git, Ref: query.Get("ref"), Date: query.Get("date"), ReleaseOnly: release, Force: force, Explain: explain}
+	if _, err := dateFormat(opts.Date); err != nil {
+		return nil, &httpError{status: http.StatusBadRequest, err: err}
+	}
+
+	switch endpoint {
+	case "version":
+		return DeriveVersion(opts)
+	case "next":
+		return s.next(query.Get("type"), opts)
+	case "tags":
+		return s.tags(r.Context(), query, opts)
+	}
+
+	return nil, &httpError{status: http.StatusNotFound, err: errors.Errorf("unknown endpoint '%s'", endpoint)}
+}
+
+func (s *server) next(nextType string, opts Options) (interface{}, error) {
+	if nextType != "patch" && nextType != "minor" && nextType != "major" {
+		return nil, badRequest("invalid type '%s'; supported types are 'patch', 'minor' and 'major'", nextType)
+	}
+
+	d, err := DeriveVersion(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return DeriveNext(d.Version, nextType, opts)
+}
+
+func (s *server) tags(ctx context.Context, query url.Values, opts Options) (interface{}, error) {
+	if s.opts.Destinations == nil {
+		return nil, &httpError{status: http.StatusNotFound, err: errors.New("no registries are configured")}
+	}
+
+	image := query.Get("image")
+	if image == "" {
+		return nil, badRequest("the image parameter is required")
+	}
+
+	d, err := DeriveVersion(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tagOpts := TagOptions{}
+	if s.opts.TagOptions != nil {
+		tagOpts = s.opts.TagOptions(image)
+	}
+
+	if v := query.Get("variant"); v != "" {
+		tagOpts.Variant = v
+	}
+
+	for param, value := range map[string]*bool{"channels": &tagOpts.Channels, "edge": &tagOpts.Edge} {
+		if v := query.Get(param); v != "" {
+			if *value, err = boolParam(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	destinations, err := s.opts.Destinations(image)
+	if err != nil {
+		return nil, err
+	}
+
+	registryOpts := append(append([]RegistryOption{}, s.opts.RegistryOptions...), WithContext(ctx))
+	sets, err := ListDestinationTags(destinations, registryOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := DeriveTags(d.Version, UnionTags(sets), tagOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return tagsResponse{Version: d.Version, TagsDerivation: *tags, Drift: TagDrift(sets)}, nil
+}
+
+func (s *server) list(query url.Values, opts ListOptions) (interface{}, error) {
+	opts.Prefix = query.Get("prefix")
+	opts.Since = query.Get("since")
+	opts.LatestPer = query.Get("latest_per")
+
+	includePreReleases, err := boolParam(query.Get("include_pre_releases"))
+	if err != nil {
+		return nil, err
+	}
+	opts.IncludePreReleases = includePreReleases
+
+	if constraint := query.Get("constraint"); constraint != "" {
+		opts.Constraint, err = ParseConstraint(constraint)
+		if err != nil {
+			return nil, &httpError{status: http.StatusBadRequest, err: err}
+		}
+	}
+
+	return ListTags(opts)
+}
+
+func boolParam(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, badRequest("invalid boolean '%s'", value)
+	}
+
+	return b, nil
+}
+
+// errorStatus returns the status of the response for an error.
+func errorStatus(err error) int {
+	var (
+		httpErr   *httpError
+		notSemver *ErrNotSemver
+		rateLimit *RateLimitError
+	)
+
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr.status
+	case errors.Is(err, ErrUnknownRef):
+		return http.StatusNotFound
+	case errors.As(err, &notSemver):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrNotOnTag), errors.Is(err, ErrDirty), errors.Is(err, ErrVersionExists):
+		return http.StatusConflict
+	case errors.Is(err, ErrRegistryAuth):
+		return http.StatusBadGateway
+	case errors.As(err, &rateLimit):
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,144 @@
+package sver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("server", func() {
+	var (
+		repo     *svertest.Repo
+		registry *svertest.Registry
+		mirror   string
+		server   *httptest.Server
+	)
+
+	BeforeEach(func() {
+		repo = svertest.NewRepo(GinkgoT()).Commit("initial").Tag("v1.2.0")
+		registry = svertest.NewRegistry(GinkgoT())
+		registry.PushImage("org/app", "1.1.0", "1.1", "1", "latest")
+
+		var err error
+		mirror, err = os.MkdirTemp("", "sver-mirror")
+		Expect(err).ToNot(HaveOccurred())
+		repo.Git("clone", "--quiet", "--bare", repo.Dir, filepath.Join(mirror, "app.git"))
+
+		server = httptest.NewServer(sver.NewServer(sver.ServerOptions{
+			Repos: map[string]string{
+				"org/app": filepath.Join(mirror, "app.git"),
+				"work":    repo.Dir,
+			},
+			Destinations: func(image string) ([]sver.Destination, error) {
+				return []sver.Destination{{Repository: registry.Repository(image)}}, nil
+			},
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		registry.Close()
+		repo.Close()
+		Expect(os.RemoveAll(mirror)).To(Succeed())
+	})
+
+	get := func(path string, result interface{}) int {
+		resp, err := http.Get(server.URL + path)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(json.NewDecoder(resp.Body).Decode(result)).To(Succeed())
+
+		return resp.StatusCode
+	}
+
+	It("answers health checks", func() {
+		result := map[string]string{}
+		Expect(get("/healthz", &result)).To(Equal(http.StatusOK))
+		Expect(result).To(Equal(map[string]string{"status": "ok"}))
+	})
+
+	It("lists the repositories", func() {
+		names := []string{}
+		Expect(get("/v1/repos", &names)).To(Equal(http.StatusOK))
+		Expect(names).To(Equal([]string{"org/app", "work"}))
+	})
+
+	It("returns the current version of a mirror", func() {
+		d := sver.Derivation{}
+		Expect(get("/v1/repos/org/app/version", &d)).To(Equal(http.StatusOK))
+		Expect(d.Version).To(Equal("1.2.0"))
+		Expect(d.Steps).ToNot(BeEmpty())
+	})
+
+	It("returns the version at a ref", func() {
+		d := sver.Derivation{}
+		Expect(get("/v1/repos/org/app/version?ref=v1.2.0", &d)).To(Equal(http.StatusOK))
+		Expect(d.Version).To(Equal("1.2.0"))
+		Expect(d.Ref).To(Equal("v1.2.0"))
+
+		result := map[string]string{}
+		Expect(get("/v1/repos/org/app/version?ref=nope", &result)).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns the next version", func() {
+		d := sver.NextDerivation{}
+		Expect(get("/v1/repos/org/app/next?type=minor", &d)).To(Equal(http.StatusOK))
+		Expect(d.Version).To(Equal("1.3.0"))
+	})
+
+	It("returns the tags of an image", func() {
+		result := struct {
+			Version string   `json:"version"`
+			Tags    []string `json:"tags"`
+		}{}
+		Expect(get("/v1/repos/org/app/tags?image=org/app", &result)).To(Equal(http.StatusOK))
+		Expect(result.Version).To(Equal("1.2.0"))
+		Expect(result.Tags).To(Equal([]string{"1.2.0", "1.2", "1", "latest"}))
+	})
+
+	It("lists the tags of a repository", func() {
+		tags := []sver.Tag{}
+		Expect(get("/v1/repos/org/app/list?since=1.0.0", &tags)).To(Equal(http.StatusOK))
+		Expect(tags).To(HaveLen(1))
+		Expect(tags[0].Name).To(Equal("v1.2.0"))
+	})
+
+	It("fails for a release of a dirty clone", func() {
+		repo.Dirty("notes.txt")
+
+		result := map[string]string{}
+		Expect(get("/v1/repos/work/version?release=true", &result)).To(Equal(http.StatusConflict))
+		Expect(result["error"]).To(ContainSubstring("version is dirty"))
+	})
+
+	It("rejects invalid parameters", func() {
+		result := map[string]string{}
+		Expect(get("/v1/repos/org/app/next?type=huge", &result)).To(Equal(http.StatusBadRequest))
+		Expect(get("/v1/repos/org/app/tags", &result)).To(Equal(http.StatusBadRequest))
+		Expect(get("/v1/repos/org/app/version?force=maybe", &result)).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns 404 for unknown repositories", func() {
+		result := map[string]string{}
+		Expect(get("/v1/repos/org/other/version", &result)).To(Equal(http.StatusNotFound))
+		Expect(result["error"]).To(Equal("unknown repository 'org/other'"))
+	})
+
+	It("only accepts GET requests", func() {
+		resp, err := http.Post(server.URL+"/v1/repos/org/app/version", "application/json", nil)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
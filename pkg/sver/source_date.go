@@ -0,0 +1,85 @@
+package sver
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SourceDateEpochEnv is the environment variable that reproducible builds use
+// to share the date of the source, as a Unix timestamp. See
+// https://reproducible-builds.org/specs/source-date-epoch/. The sver command
+// copies it to the SourceDateEpoch of Options.
+const SourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// SourceDate returns the date of the source at the ref of opts, in UTC: the
+// date of SourceDateEpoch if it's set and the ref is HEAD, or the committer
+// or author date of the commit, as chosen by the Date option. It's the date of
+// development versions and of the build info.
+func SourceDate(opts Options) (time.Time, error) {
+	r := opts.repo()
+	if err := r.verify(); err != nil {
+		return time.Time{}, errors.Wrap(err, "git error")
+	}
+
+	rev, err := r.resolve(opts.ref())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	date, _, err := r.sourceDate(rev, opts)
+
+	return date, err
+}
+
+// sourceDate returns the date of the source at rev, and where it comes from,
+// to explain it.
+func (r repo) sourceDate(rev string, opts Options) (time.Time, string, error) {
+	format, err := dateFormat(opts.Date)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	if epoch := opts.SourceDateEpoch; epoch != "" && rev == "HEAD" {
+		unixTime, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil || unixTime < 0 {
+			return time.Time{}, "", errors.Errorf("invalid %s '%s'; it must be a Unix timestamp", SourceDateEpochEnv, epoch)
+		}
+
+		return time.Unix(unixTime, 0).UTC(), SourceDateEpochEnv + " variable", nil
+	}
+
+	out, err := r.git("show", "--no-patch", "--format="+format, rev)
+	if err != nil {
+		return time.Time{}, "", errors.Wrap(err, "exec error")
+	}
+
+	unixTime, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, "", errors.Wrap(err, "failed to parse git commit timestamp")
+	}
+
+	return time.Unix(unixTime, 0).UTC(), dateOrDefault(opts.Date) + " date", nil
+}
+
+// dateFormat returns the git format of the Unix timestamp of a commit date,
+// 'committer' or 'author'. The committer date is the default.
+func dateFormat(date string) (string, error) {
+	switch dateOrDefault(date) {
+	case "committer":
+		return "%ct", nil
+	case "author":
+		return "%at", nil
+	}
+
+	return "", errors.Errorf("invalid date '%s'; supported dates are 'committer' and 'author'", date)
+}
+
+func dateOrDefault(date string) string {
+	if date == "" {
+		return "committer"
+	}
+
+	return date
+}
@@ -0,0 +1,118 @@
+package sver_test
+
+import (
+	"os"
+	"time"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("source date", func() {
+	var repo *svertest.Repo
+
+	BeforeEach(func() {
+		repo = svertest.NewRepo(GinkgoT()).
+			Commit("initial").Tag("v1.2.0").
+			At(time.Date(2021, time.March, 4, 5, 6, 0, 0, time.UTC)).Commit("fix")
+	})
+
+	AfterEach(func() {
+		repo.Close()
+	})
+
+	It("formats the timestamp in UTC, whatever the time zone", func() {
+		local := time.Local
+		time.Local = time.FixedZone("UTC-5", -5*60*60)
+		defer func() { time.Local = local }()
+
+		d, err := sver.DeriveVersion(repo.Options())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Timestamp).To(Equal("20210304050600"))
+	})
+
+	It("uses SourceDateEpoch at HEAD", func() {
+		opts := repo.Options()
+		opts.SourceDateEpoch = "1700000000"
+
+		d, err := sver.DeriveVersion(opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Timestamp).To(Equal("20231114221320"))
+		Expect(d.Steps).To(ContainElement(ContainSubstring("from the SOURCE_DATE_EPOCH variable")))
+
+		date, err := sver.SourceDate(opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(date).To(Equal(time.Unix(1700000000, 0).UTC()))
+
+		info, err := sver.DeriveBuildInfo("1.2.0", opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Date).To(Equal("2023-11-14T22:13:20Z"))
+	})
+
+	It("doesn't read SOURCE_DATE_EPOCH from the environment", func() {
+		Expect(os.Setenv(sver.SourceDateEpochEnv, "1700000000")).To(Succeed())
+		defer os.Unsetenv(sver.SourceDateEpochEnv)
+
+		d, err := sver.DeriveVersion(repo.Options())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Timestamp).To(Equal("20210304050600"))
+	})
+
+	It("ignores SourceDateEpoch at another ref", func() {
+		repo.Commit("more")
+
+		d, err := sver.DeriveVersion(sver.Options{Dir: repo.Dir, Ref: "HEAD~1", SourceDateEpoch: "1700000000"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Timestamp).To(Equal("20210304050600"))
+	})
+
+	It("fails for an invalid SourceDateEpoch", func() {
+		_, err := sver.DeriveVersion(sver.Options{Dir: repo.Dir, SourceDateEpoch: "yesterday"})
+		Expect(err).To(MatchError("invalid SOURCE_DATE_EPOCH 'yesterday'; it must be a Unix timestamp"))
+	})
+
+	It("returns the commit date", func() {
+		date, err := sver.SourceDate(repo.Options())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(date).To(Equal(time.Date(2021, time.March, 4, 5, 6, 0, 0, time.UTC)))
+	})
+
+	It("fails for an unknown date", func() {
+		_, err := sver.DeriveVersion(sver.Options{Dir: repo.Dir, Date: "tagger"})
+		Expect(err).To(MatchError("invalid date 'tagger'; supported dates are 'committer' and 'author'"))
+	})
+
+	Context("with a fake git backend", func() {
+		fake := func() *svertest.FakeGit {
+			return svertest.NewFakeVersion(svertest.FakeVersion{
+				Tag:        "v1.2.0",
+				Distance:   1,
+				CommitDate: time.Date(2021, time.March, 4, 5, 6, 0, 0, time.UTC),
+				AuthorDate: time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC),
+				ShortHash:  "4fc2e9e5",
+			})
+		}
+
+		It("uses the committer date by default", func() {
+			d, err := sver.DeriveVersion(sver.Options{Git: fake()})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Version).To(Equal("1.2.0-20210304050600.1.g4fc2e9e5"))
+		})
+
+		It("uses the author date", func() {
+			d, err := sver.DeriveVersion(sver.Options{Git: fake(), Date: "author"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Version).To(Equal("1.2.0-20200102030405.1.g4fc2e9e5"))
+		})
+	})
+})
@@ -0,0 +1,325 @@
+package sver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Updater writes a version into the content of a project file, leaving the
+// rest of the content as is.
+type Updater interface {
+	Update(content []byte, version string) ([]byte, error)
+}
+
+// JSONUpdater sets the string value at a dot-separated path of object keys,
+// like `version` in a package.json.
+type JSONUpdater struct {
+	Path string
+}
+
+// YAMLUpdater sets the scalar value at a dot-separated path of mapping keys,
+// like `appVersion` in a Chart.yaml.
+type YAMLUpdater struct {
+	Path string
+}
+
+// TOMLUpdater sets the string value of a key, prefixed with the name of its
+// table, like `package.version` in a Cargo.toml or `project.version` in a
+// pyproject.toml.
+type TOMLUpdater struct {
+	Key string
+}
+
+// RegexUpdater replaces the first capture group of every match of a regular
+// expression, like `Version = "(.*)"` in a version.go.
+type RegexUpdater struct {
+	Pattern string
+}
+
+// StampTarget is a file to stamp, with the updater to use. Exactly one of JSON,
+// YAML, TOML or Regex must be set.
+type StampTarget struct {
+	File  string `yaml:"file" json:"file"`
+	JSON  string `yaml:"json" json:"json"`
+	YAML  string `yaml:"yaml" json:"yaml"`
+	TOML  string `yaml:"toml" json:"toml"`
+	Regex string `yaml:"regex" json:"regex"`
+}
+
+// Updater returns the updater of the target.
+func (t StampTarget) Updater() (Updater, error) {
+	updaters := []Updater{}
+	if t.JSON != "" {
+		updaters = append(updaters, JSONUpdater{Path: t.JSON})
+	}
+	if t.YAML != "" {
+		updaters = append(updaters, YAMLUpdater{Path: t.YAML})
+	}
+	if t.TOML != "" {
+		updaters = append(updaters, TOMLUpdater{Key: t.TOML})
+	}
+	if t.Regex != "" {
+		if _, err := compileStampRegex(t.Regex); err != nil {
+			return nil, err
+		}
+		updaters = append(updaters, RegexUpdater{Pattern: t.Regex})
+	}
+
+	if t.File == "" {
+		return nil, errors.New("stamp target has no file")
+	}
+
+	if len(updaters) != 1 {
+		return nil, errors.Errorf("stamp target [%s] must set exactly one of 'json', 'yaml', 'toml' or 'regex'", t.File)
+	}
+
+	return updaters[0], nil
+}
+
+// StampFile writes version into a file with an updater. It returns true if the
+// file was out of date. With check, the file isn't written.
+func StampFile(path string, updater Updater, version string, check bool) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read [%s]", path)
+	}
+
+	updated, err := updater.Update(content, version)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to update [%s]", path)
+	}
+
+	if bytes.Equal(content, updated) {
+		return false, nil
+	}
+
+	if check {
+		return true, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.WriteFile(path, updated, info.Mode())
+}
+
+// Update implements Updater.
+func (u JSONUpdater) Update(content []byte, version string) ([]byte, error) {
+	path := strings.Split(u.Path, ".")
+
+	type frame struct {
+		object    bool
+		key       string
+		expectKey bool
+	}
+	stack := []*frame{}
+
+	valueDone := func() {
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].expectKey = true
+		}
+	}
+
+	matches := func() bool {
+		if len(stack) != len(path) {
+			return false
+		}
+		for i, f := range stack {
+			if !f.object || f.key != path[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return nil, errors.Errorf("'%s' not found", u.Path)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				stack = append(stack, &frame{object: true, expectKey: true})
+			case '[':
+				stack = append(stack, &frame{})
+			default:
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+		case string:
+			top := (*frame)(nil)
+			if len(stack) > 0 {
+				top = stack[len(stack)-1]
+			}
+
+			if top == nil || !top.object || !top.expectKey {
+				valueDone()
+				continue
+			}
+
+			top.key = t
+			top.expectKey = false
+
+			if matches() {
+				return replaceJSONString(content, int(dec.InputOffset()), version, u.Path)
+			}
+		default:
+			valueDone()
+		}
+	}
+}
+
+// replaceJSONString replaces the string value that follows the key ending at
+// offset.
+func replaceJSONString(content []byte, offset int, version, path string) ([]byte, error) {
+	start := offset
+	for start < len(content) && (isJSONSpace(content[start]) || content[start] == ':') {
+		start++
+	}
+
+	if start >= len(content) || content[start] != '"' {
+		return nil, errors.Errorf("'%s' isn't a string value", path)
+	}
+
+	end := start + 1
+	for end < len(content) && content[end] != '"' {
+		if content[end] == '\\' {
+			end++
+		}
+		end++
+	}
+
+	if end >= len(content) {
+		return nil, errors.Errorf("unterminated string value of '%s'", path)
+	}
+
+	quoted, err := json.Marshal(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return spliceBytes(content, start, end+1, quoted), nil
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// Update implements Updater.
+func (u YAMLUpdater) Update(content []byte, version string) ([]byte, error) {
+	return setYAMLValue(content, strings.Split(u.Path, "."), version, false)
+}
+
+var regexTOMLTable = regexp.MustCompile(`^\s*\[\s*([^\[\]]+?)\s*\]\s*(#.*)?$`)
+
+// Update implements Updater.
+func (u TOMLUpdater) Update(content []byte, version string) ([]byte, error) {
+	table := ""
+	key := u.Key
+	if i := strings.LastIndex(u.Key, "."); i != -1 {
+		table, key = u.Key[:i], u.Key[i+1:]
+	}
+
+	keyPattern, err := regexp.Compile(`^(\s*` + regexp.QuoteMeta(key) + `\s*=\s*)("[^"]*"|'[^']*')`)
+	if err != nil {
+		return nil, err
+	}
+
+	current := ""
+	offset := 0
+	for _, rawLine := range bytes.SplitAfter(content, []byte("\n")) {
+		line := strings.TrimRight(string(rawLine), "\r\n")
+		lineStart := offset
+		offset += len(rawLine)
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[[") {
+			// Keys in arrays of tables aren't supported.
+			current = "\x00"
+			continue
+		}
+
+		if m := regexTOMLTable.FindStringSubmatch(line); m != nil {
+			current = m[1]
+			continue
+		}
+
+		if current != table {
+			continue
+		}
+
+		m := keyPattern.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+
+		quote := line[m[4]]
+		start, end := lineStart+m[4], lineStart+m[5]
+
+		return spliceBytes(content, start, end, []byte(string(quote)+version+string(quote))), nil
+	}
+
+	return nil, errors.Errorf("'%s' not found", u.Key)
+}
+
+// Update implements Updater.
+func (u RegexUpdater) Update(content []byte, version string) ([]byte, error) {
+	re, err := compileStampRegex(u.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := re.FindAllSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return nil, errors.Errorf("no match for '%s'", u.Pattern)
+	}
+
+	updated := []byte{}
+	last := 0
+	for _, m := range matches {
+		if m[2] == -1 {
+			continue
+		}
+		updated = append(updated, content[last:m[2]]...)
+		updated = append(updated, version...)
+		last = m[3]
+	}
+
+	return append(updated, content[last:]...), nil
+}
+
+func compileStampRegex(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid regex '%s'", pattern)
+	}
+
+	if re.NumSubexp() < 1 {
+		return nil, errors.Errorf("regex '%s' must have a capture group for the version", pattern)
+	}
+
+	return re, nil
+}
+
+func spliceBytes(content []byte, start, end int, value []byte) []byte {
+	result := make([]byte, 0, len(content)-(end-start)+len(value))
+	result = append(result, content[:start]...)
+	result = append(result, value...)
+
+	return append(result, content[end:]...)
+}
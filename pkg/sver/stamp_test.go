@@ -0,0 +1,138 @@
+package sver_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("stamp", func() {
+	update := func(updater sver.Updater, content string) string {
+		updated, err := updater.Update([]byte(content), "1.4.0")
+		Expect(err).ToNot(HaveOccurred())
+		return string(updated)
+	}
+
+	Context("JSON", func() {
+		It("updates a top-level key and keeps the formatting", func() {
+			content := "{\n  \"name\": \"app\",\n  \"version\" :  \"1.0.0\",\n  \"deps\": {\"version\": \"9.9.9\"}\n}\n"
+			Expect(update(sver.JSONUpdater{Path: "version"}, content)).To(Equal(
+				"{\n  \"name\": \"app\",\n  \"version\" :  \"1.4.0\",\n  \"deps\": {\"version\": \"9.9.9\"}\n}\n"))
+		})
+
+		It("updates a nested key", func() {
+			content := `{"items": [{"version": "0.1.0"}], "app": {"meta": {"version": "1.0.0"}}}`
+			Expect(update(sver.JSONUpdater{Path: "app.meta.version"}, content)).To(Equal(
+				`{"items": [{"version": "0.1.0"}], "app": {"meta": {"version": "1.4.0"}}}`))
+		})
+
+		It("fails if the key is missing or isn't a string", func() {
+			_, err := sver.JSONUpdater{Path: "version"}.Update([]byte(`{"name": "version"}`), "1.4.0")
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+
+			_, err = sver.JSONUpdater{Path: "version"}.Update([]byte(`{"version": 1}`), "1.4.0")
+			Expect(err).To(MatchError(ContainSubstring("isn't a string")))
+		})
+	})
+
+	Context("YAML", func() {
+		It("updates a nested key and keeps comments", func() {
+			content := "# app\nimage:\n  tag: '1.0.0' # pinned\n  name: app\n"
+			Expect(update(sver.YAMLUpdater{Path: "image.tag"}, content)).To(Equal(
+				"# app\nimage:\n  tag: '1.4.0' # pinned\n  name: app\n"))
+		})
+
+		It("fails if the key is missing", func() {
+			_, err := sver.YAMLUpdater{Path: "version"}.Update([]byte("name: app\n"), "1.4.0")
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+		})
+	})
+
+	Context("TOML", func() {
+		It("updates a key in a table", func() {
+			content := "[package]\nname = \"app\"\nversion = \"1.0.0\" # bumped in CI\n\n[dependencies]\nversion = \"2\"\n"
+			Expect(update(sver.TOMLUpdater{Key: "package.version"}, content)).To(Equal(
+				"[package]\nname = \"app\"\nversion = \"1.4.0\" # bumped in CI\n\n[dependencies]\nversion = \"2\"\n"))
+		})
+
+		It("updates a top-level key with CRLF line endings", func() {
+			content := "version = '1.0.0'\r\n[tool]\r\nversion = \"2\"\r\n"
+			Expect(update(sver.TOMLUpdater{Key: "version"}, content)).To(Equal(
+				"version = '1.4.0'\r\n[tool]\r\nversion = \"2\"\r\n"))
+		})
+
+		It("fails if the key is missing", func() {
+			_, err := sver.TOMLUpdater{Key: "project.version"}.Update([]byte("[package]\nversion = \"1\"\n"), "1.4.0")
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+		})
+	})
+
+	Context("regex", func() {
+		It("replaces the capture group of every match", func() {
+			content := "package version\n\nconst Version = \"1.0.0\"\nconst Other = \"1.0.0\"\n"
+			Expect(update(sver.RegexUpdater{Pattern: `Version = "(.*)"`}, content)).To(Equal(
+				"package version\n\nconst Version = \"1.4.0\"\nconst Other = \"1.0.0\"\n"))
+		})
+
+		It("fails without a match", func() {
+			_, err := sver.RegexUpdater{Pattern: `Version = "(.*)"`}.Update([]byte("package version\n"), "1.4.0")
+			Expect(err).To(MatchError(ContainSubstring("no match")))
+		})
+	})
+
+	Context("targets", func() {
+		It("requires exactly one updater", func() {
+			_, err := sver.StampTarget{File: "package.json"}.Updater()
+			Expect(err).To(HaveOccurred())
+
+			_, err = sver.StampTarget{File: "package.json", JSON: "version", YAML: "version"}.Updater()
+			Expect(err).To(HaveOccurred())
+
+			_, err = sver.StampTarget{File: "version.go", Regex: `Version = ".*"`}.Updater()
+			Expect(err).To(MatchError(ContainSubstring("capture group")))
+
+			updater, err := sver.StampTarget{File: "Cargo.toml", TOML: "package.version"}.Updater()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updater).To(Equal(sver.TOMLUpdater{Key: "package.version"}))
+		})
+	})
+
+	Context("stamping files", func() {
+		var path string
+
+		BeforeEach(func() {
+			dir, err := os.MkdirTemp("", "sver-stamp")
+			Expect(err).ToNot(HaveOccurred())
+			path = filepath.Join(dir, "package.json")
+			Expect(os.WriteFile(path, []byte(`{"version": "1.0.0"}`), 0600)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(filepath.Dir(path))).To(Succeed())
+		})
+
+		It("only reports out of date files in check mode", func() {
+			changed, err := sver.StampFile(path, sver.JSONUpdater{Path: "version"}, "1.4.0", true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changed).To(BeTrue())
+
+			content, err := os.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal(`{"version": "1.0.0"}`))
+		})
+
+		It("writes the version", func() {
+			changed, err := sver.StampFile(path, sver.JSONUpdater{Path: "version"}, "1.4.0", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changed).To(BeTrue())
+
+			changed, err = sver.StampFile(path, sver.JSONUpdater{Path: "version"}, "1.4.0", true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changed).To(BeFalse())
+		})
+	})
+})
@@ -12,6 +12,14 @@ var (
 )
 
 func TestCalcVersion(t *testing.T) {
+	// Commits made by the tests don't depend on the git identity of the host.
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(env, "sver")
+	}
+	for _, env := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "sver@example.com")
+	}
+
 	RegisterFailHandler(Fail)
 	ginkgoT = t
 	RunSpecs(t, "sver suite")
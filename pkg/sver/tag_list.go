@@ -0,0 +1,71 @@
+package sver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReadTagList reads a list of existing tags, to calculate tags without access
+// to a registry. It accepts one tag per line, like the output of `crane ls`,
+// where empty lines and lines starting with `#` are ignored. It also accepts
+// JSON: an array of tags, or an object with a `tags` field, like the output of
+// `skopeo list-tags` or the registry tags API.
+func ReadTagList(r io.Reader) ([]string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read tag list")
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return parseJSONTagList(trimmed)
+	}
+
+	tags := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		tags = append(tags, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read tag list")
+	}
+
+	return tags, nil
+}
+
+func parseJSONTagList(content []byte) ([]string, error) {
+	if content[0] == '[' {
+		tags := []string{}
+		if err := json.Unmarshal(content, &tags); err != nil {
+			return nil, errors.Wrap(err, "failed to parse tag list")
+		}
+
+		return tags, nil
+	}
+
+	// Field names are matched case-insensitively, so this also reads the
+	// `Tags` field of skopeo.
+	list := struct {
+		Tags []string `json:"tags"`
+	}{}
+	if err := json.Unmarshal(content, &list); err != nil {
+		return nil, errors.Wrap(err, "failed to parse tag list")
+	}
+
+	if list.Tags == nil {
+		return []string{}, nil
+	}
+
+	return list.Tags, nil
+}
@@ -0,0 +1,48 @@
+package sver_test
+
+import (
+	"strings"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("tag-list", func() {
+	read := func(content string) []string {
+		tags, err := sver.ReadTagList(strings.NewReader(content))
+		Expect(err).ToNot(HaveOccurred())
+
+		return tags
+	}
+
+	It("reads one tag per line", func() {
+		Expect(read("1.0.0\n1.1.0\r\n\n# a comment\n  latest  \n")).To(Equal([]string{"1.0.0", "1.1.0", "latest"}))
+	})
+
+	It("reads an empty list", func() {
+		Expect(read("")).To(BeEmpty())
+	})
+
+	It("reads a JSON array", func() {
+		Expect(read(`["1.0.0", "latest"]`)).To(Equal([]string{"1.0.0", "latest"}))
+	})
+
+	It("reads the registry tags API response", func() {
+		Expect(read(`{"name": "org/image", "tags": ["1.0.0", "latest"]}`)).To(Equal([]string{"1.0.0", "latest"}))
+	})
+
+	It("reads the output of skopeo list-tags", func() {
+		content := `{
+			"Repository": "ghcr.io/org/image",
+			"Tags": ["1.0.0", "latest"]
+		}`
+		Expect(read(content)).To(Equal([]string{"1.0.0", "latest"}))
+	})
+
+	It("fails on invalid JSON", func() {
+		_, err := sver.ReadTagList(strings.NewReader(`{"tags": [1.0.0]}`))
+		Expect(err).To(HaveOccurred())
+	})
+})
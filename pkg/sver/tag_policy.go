@@ -0,0 +1,170 @@
+package sver
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+)
+
+// TagCondition decides whether a floating tag applies to a release version.
+type TagCondition string
+
+const (
+	// Always applies the tag to every release.
+	Always TagCondition = "always"
+	// LatestInMinor applies the tag if the release is higher than all existing
+	// versions in the same X.Y series, including pre-releases.
+	LatestInMinor TagCondition = "latest-in-minor"
+	// LatestInMajor applies the tag if the release is higher than all existing
+	// versions in the same X series, including pre-releases.
+	LatestInMajor TagCondition = "latest-in-major"
+	// GlobalLatest applies the tag if the release is higher than all existing
+	// versions, including pre-releases.
+	GlobalLatest TagCondition = "latest"
+)
+
+// TagRule is a floating tag, rendered from a template, that's added when its
+// condition holds. Templates use text/template syntax, with the fields of
+// TagData, like `v{{.Major}}.{{.Minor}}`.
+type TagRule struct {
+	Template string       `yaml:"template" json:"template"`
+	When     TagCondition `yaml:"when" json:"when"`
+}
+
+// TagPolicy lists the floating tags added to release versions, besides the
+// full version tag, which is always returned.
+type TagPolicy []TagRule
+
+// TagData is available to tag templates.
+type TagData struct {
+	Version string
+	Major   int64
+	Minor   int64
+	Patch   int64
+}
+
+// DefaultTagPolicy returns the `X.Y`, `X` and `latest` tags.
+func DefaultTagPolicy() TagPolicy {
+	return TagPolicy{
+		{Template: "{{.Major}}.{{.Minor}}", When: LatestInMinor},
+		{Template: "{{.Major}}", When: LatestInMajor},
+		{Template: "latest", When: GlobalLatest},
+	}
+}
+
+// ParseTagRule parses a rule in the `<condition>=<template>` format, like
+// `latest-in-minor=v{{.Major}}.{{.Minor}}`.
+func ParseTagRule(rule string) (TagRule, error) {
+	parts := strings.SplitN(rule, "=", 2)
+	if len(parts) != 2 {
+		return TagRule{}, errors.Errorf("tag rule '%s' isn't in the <condition>=<template> format", rule)
+	}
+
+	tagRule := TagRule{When: TagCondition(parts[0]), Template: parts[1]}
+
+	return tagRule, tagRule.Validate()
+}
+
+// Validate checks that the condition is known and the template parses.
+func (r TagRule) Validate() error {
+	switch r.When {
+	case Always, LatestInMinor, LatestInMajor, GlobalLatest:
+	default:
+		return errors.Errorf("unknown tag condition '%s'; supported conditions are '%s', '%s', '%s' and '%s'",
+			r.When, Always, LatestInMinor, LatestInMajor, GlobalLatest)
+	}
+
+	if _, err := r.parse(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate checks all rules of the policy.
+func (p TagPolicy) Validate() error {
+	for _, rule := range p {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r TagRule) parse() (*template.Template, error) {
+	tmpl, err := template.New("tag").Option("missingkey=error").Parse(r.Template)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid tag template '%s'", r.Template)
+	}
+
+	return tmpl, nil
+}
+
+func (r TagRule) render(v *semver.Version) (string, error) {
+	tmpl, err := r.parse()
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, TagData{
+		Version: v.String(),
+		Major:   v.Major(),
+		Minor:   v.Minor(),
+		Patch:   v.Patch(),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to render tag template '%s'", r.Template)
+	}
+
+	tag := strings.TrimSpace(buf.String())
+	if tag == "" {
+		return "", errors.Errorf("tag template '%s' rendered an empty tag", r.Template)
+	}
+
+	return tag, nil
+}
+
+// decide returns a decision for the tag of the condition, given the existing
+// versions. If the condition doesn't hold, the decision names the existing
+// version that blocks it.
+func (c TagCondition) decide(tag string, v *semver.Version, existing []*semver.Version) TagDecision {
+	var inScope func(e *semver.Version) bool
+	scope := ""
+	switch c {
+	case LatestInMinor:
+		inScope = func(e *semver.Version) bool { return sameMinor(e, v) }
+		scope = fmt.Sprintf("version of %d.%d", v.Major(), v.Minor())
+	case LatestInMajor:
+		inScope = func(e *semver.Version) bool { return e.Major() == v.Major() }
+		scope = fmt.Sprintf("version of %d", v.Major())
+	case GlobalLatest:
+		inScope = func(*semver.Version) bool { return true }
+		scope = "version"
+	default:
+		return TagDecision{Tag: tag, Added: true, Reason: fmt.Sprintf("the '%s' rule always applies", c)}
+	}
+
+	return decideLatest(tag, v, existing, inScope, scope)
+}
+
+// apply returns the decisions for the floating tags of the policy for a
+// release version.
+func (p TagPolicy) apply(v *semver.Version, existing []*semver.Version) ([]TagDecision, error) {
+	decisions := []TagDecision{}
+	for _, rule := range p {
+		tag, err := rule.render(v)
+		if err != nil {
+			return nil, err
+		}
+
+		decisions = append(decisions, rule.When.decide(tag, v, existing))
+	}
+
+	return decisions, nil
+}
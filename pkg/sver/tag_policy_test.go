@@ -0,0 +1,86 @@
+package sver_test
+
+import (
+	"github.com/aserto-dev/sver/pkg/sver"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("tag-policy", func() {
+	Context("parsing rules", func() {
+		It("parses a condition and a template", func() {
+			rule, err := sver.ParseTagRule("latest-in-minor=v{{.Major}}.{{.Minor}}")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(rule).To(Equal(sver.TagRule{When: sver.LatestInMinor, Template: "v{{.Major}}.{{.Minor}}"}))
+		})
+
+		It("fails without a condition", func() {
+			_, err := sver.ParseTagRule("{{.Major}}")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails with an unknown condition", func() {
+			_, err := sver.ParseTagRule("sometimes={{.Major}}")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails with an invalid template", func() {
+			_, err := sver.ParseTagRule("always={{.Major")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("calculating tags", func() {
+		existingTags := []string{"1.2.0", "1.3.0", "2.0.0"}
+
+		calculate := func(version string, policy sver.TagPolicy) []string {
+			tags, err := sver.CalculateTags(version, existingTags, sver.TagOptions{Policy: policy})
+			Expect(err).ToNot(HaveOccurred())
+
+			return tags
+		}
+
+		It("uses the default policy if none is set", func() {
+			Expect(calculate("2.0.1", nil)).To(Equal([]string{"2.0.1", "2.0", "2", "latest"}))
+		})
+
+		It("can replace latest with stable", func() {
+			policy := sver.TagPolicy{
+				{Template: "{{.Major}}.{{.Minor}}", When: sver.LatestInMinor},
+				{Template: "stable", When: sver.GlobalLatest},
+			}
+
+			Expect(calculate("2.0.1", policy)).To(Equal([]string{"2.0.1", "2.0", "stable"}))
+		})
+
+		It("can add prefixed and suffixed tags", func() {
+			policy := sver.TagPolicy{
+				{Template: "{{.Major}}.{{.Minor}}", When: sver.LatestInMinor},
+				{Template: "v{{.Major}}.{{.Minor}}", When: sver.LatestInMinor},
+				{Template: "{{.Major}}.{{.Minor}}-alpine", When: sver.LatestInMinor},
+				{Template: "{{.Version}}-alpine", When: sver.Always},
+			}
+
+			Expect(calculate("1.3.1", policy)).To(Equal([]string{"1.3.1", "1.3", "v1.3", "1.3-alpine", "1.3.1-alpine"}))
+		})
+
+		It("only adds tags whose condition holds", func() {
+			Expect(calculate("1.2.1", nil)).To(Equal([]string{"1.2.1", "1.2"}))
+		})
+
+		It("doesn't apply the policy to pre-releases", func() {
+			policy := sver.TagPolicy{{Template: "stable", When: sver.Always}}
+
+			Expect(calculate("3.0.0-rc.1", policy)).To(Equal([]string{"3.0.0-rc.1"}))
+		})
+
+		It("fails if a template renders an empty tag", func() {
+			policy := sver.TagPolicy{{Template: "{{if false}}x{{end}}", When: sver.Always}}
+
+			_, err := sver.CalculateTags("1.3.1", existingTags, sver.TagOptions{Policy: policy})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -3,10 +3,12 @@ package sver
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
 )
 
@@ -21,29 +23,124 @@ var (
 	regexTail  = regexp.MustCompile(`^\d+\.\d+\.\d+(.*)`)
 )
 
+// Options configure how the current version is derived.
+type Options struct {
+	// Dir is the directory of the repository. It defaults to the current
+	// directory.
+	Dir string
+	// Git runs the git commands. It defaults to ExecGit.
+	Git GitBackend
+	// Ref is the commit, branch or tag to derive the version of. It defaults
+	// to HEAD. The tree is only checked for uncommitted changes at HEAD.
+	Ref string
+	// Date is the date of the commit in development versions, 'committer' or
+	// 'author'. It defaults to the committer date. At HEAD, SourceDateEpoch
+	// overrides it.
+	Date string
+	// SourceDateEpoch is a Unix timestamp that overrides the date at HEAD,
+	// like the SOURCE_DATE_EPOCH variable of reproducible builds. It isn't
+	// read from the environment, so callers opt in by copying the variable.
+	SourceDateEpoch string
+	// ReleaseOnly fails if Ref isn't tagged or if the tree is dirty.
+	ReleaseOnly bool
+	// Force ignores a dirty tree.
+	Force bool
+	// Explain also looks for what can make a version look wrong, like a
+	// higher tag that isn't reachable from Ref. It takes more git commands,
+	// and only adds steps to the derivation.
+	Explain bool
+}
+
+// Derivation records how the current version was derived from the
+// repository, to explain a version that looks wrong.
+type Derivation struct {
+	Version string `json:"version"`
+	// Ref is the commit, branch or tag the version was derived at.
+	Ref string `json:"ref"`
+	// Tag is the tag chosen by `git describe`, or empty if no tag is reachable
+	// from Ref.
+	Tag string `json:"tag"`
+	// OnTag is true if Ref is tagged, which makes the version a release.
+	OnTag bool `json:"onTag"`
+	// Distance, Timestamp and Commit make up the pre-release part of a
+	// development version, when Ref isn't tagged. Timestamp is in UTC.
+	Distance  int    `json:"distance"`
+	Timestamp string `json:"timestamp"`
+	Commit    string `json:"commit"`
+	// DirtyFiles are the uncommitted changes that make the version dirty.
+	Dirty      bool     `json:"dirty"`
+	DirtyFiles []string `json:"dirtyFiles"`
+	// Steps describe each decision, in order.
+	Steps []string `json:"steps"`
+}
+
+func (o Options) repo() repo {
+	return newRepo(o.Dir, o.Git)
+}
+
+func (o Options) ref() string {
+	if o.Ref == "" {
+		return "HEAD"
+	}
+
+	return o.Ref
+}
+
+func (d *Derivation) step(format string, args ...interface{}) {
+	d.Steps = append(d.Steps, fmt.Sprintf(format, args...))
+}
+
 func CurrentVersion(releaseOnly, force bool) (string, error) {
-	err := verifyGit()
+	d, err := DeriveVersion(Options{ReleaseOnly: releaseOnly, Force: force})
 	if err != nil {
-		return "", errors.Wrap(err, "git error")
+		return "", err
 	}
 
-	hasTag := true
-	tag, err := git("describe", "--tags", "--abbrev=0")
+	return d.Version, nil
+}
+
+// DeriveVersion returns the version at the ref of opts, HEAD by default, with
+// the decisions that led to it. On error, the derivation holds the decisions
+// made so far.
+func DeriveVersion(opts Options) (*Derivation, error) {
+	ref := opts.ref()
+	d := &Derivation{Ref: ref, DirtyFiles: []string{}, Steps: []string{}}
+	r := opts.repo()
+
+	err := r.verify()
 	if err != nil {
-		if !strings.Contains(err.Error(), "cannot describe anything") {
-			return "", errors.Wrap(err, "exec error")
-		} else {
-			tag = "0.0.0"
-			hasTag = false
-		}
+		return d, errors.Wrap(err, "git error")
 	}
 
-	if !regexSupportedVersionFormat.MatchString(tag) {
-		if strings.Contains(tag, "+") {
-			return "", errors.Errorf("looks like your git tag '%s' has a semver with a + sign - that's not supported by this tool", tag)
-		}
+	if _, err := dateFormat(opts.Date); err != nil {
+		return d, err
+	}
 
-		return "", errors.Errorf("'%s' doesn't seem to be a semantic version", tag)
+	rev, err := r.resolve(ref)
+	if err != nil {
+		return d, err
+	}
+
+	tag, hasTag, err := r.lastTag(rev)
+	if err != nil {
+		return d, err
+	}
+
+	if hasTag {
+		d.Tag = tag
+		d.step("git describe chose %s, the closest tag reachable from %s", tag, ref)
+	} else {
+		d.step("no tag is reachable from %s, so the version starts at 0.0.0", ref)
+	}
+
+	if opts.Explain {
+		newer, err := r.newerUnreachableTag(tag, rev)
+		if err != nil {
+			return d, err
+		}
+		if newer != "" {
+			d.step("%s is a higher version, but it's ignored because it isn't reachable from %s", newer, ref)
+		}
 	}
 
 	version := tag
@@ -51,114 +148,358 @@ func CurrentVersion(releaseOnly, force bool) (string, error) {
 	// Version starts being the last tag that points to a commit in the branch,
 	// then it gets mutated based on a series of constraints.
 
-	//  If the tag doesn't point to HEAD, it's a pre-release.
-	pointsAt, err := git("tag", "--points-at", "HEAD")
+	//  If the tag doesn't point to the ref, it's a pre-release.
+	pointsAt, err := r.git("tag", "--points-at", rev)
 	if err != nil {
-		return "", errors.Wrap(err, "exec error")
+		return d, errors.Wrap(err, "exec error")
 	}
-	if pointsAt == "" {
-		if releaseOnly {
-			return "", errors.New("not on a tag, this is a pre release version")
+	if pointsAt != "" {
+		d.OnTag = true
+		d.step("%s is tagged with %s, so this is a release version", ref, strings.Join(strings.Split(pointsAt, "\n"), ", "))
+	} else {
+		if opts.ReleaseOnly {
+			d.step("%s isn't tagged", ref)
+			return d, errors.WithStack(ErrNotOnTag)
 		}
 
 		// The commit timestamp should be in the format yyyymmddHHMMSS in UTC.
-		gitCommitTimestamp, err := git("show", "--no-patch", "--format=%ct", "HEAD")
+		date, dateSource, err := r.sourceDate(rev, opts)
 		if err != nil {
-			return "", errors.Wrap(err, "exec error")
+			return d, err
 		}
-
-		unixTime, err := strconv.ParseInt(gitCommitTimestamp, 10, 64)
-		if err != nil {
-			return "", errors.Wrap(err, "failed to parse git commit timestamp")
-		}
-		parsedTimestamp := time.Unix(unixTime, 0)
-		gitCommitTimestamp = parsedTimestamp.Format("20060102150405")
+		gitCommitTimestamp := commitTimestamp(date)
 
 		//  The number of commits since last tag that points to a commits in the
 		//  branch.
 		gitNumberCommits := "0"
 		if hasTag {
-			gitNumberCommits, err = git("rev-list", "--count", fmt.Sprintf("%s...HEAD", version))
+			gitNumberCommits, err = r.git("rev-list", "--count", fmt.Sprintf("%s...%s", version, rev))
 		}
 		if err != nil {
-			return "", errors.Wrap(err, "exec error")
+			return d, errors.Wrap(err, "exec error")
 		}
 
 		//  Add `g` to the short hash to match git describe.
-		gitCommitShortHash, err := git("rev-parse", "--short=8", "HEAD")
+		gitCommitShortHash, err := r.git("rev-parse", "--short=8", rev)
 		if err != nil {
-			return "", errors.Wrap(err, "exec error")
+			return d, errors.Wrap(err, "exec error")
 		}
 
 		gitCommitShortHash = "g" + gitCommitShortHash
 
+		d.Distance, err = strconv.Atoi(gitNumberCommits)
+		if err != nil {
+			return d, errors.Wrap(err, "failed to parse the number of commits")
+		}
+		d.Timestamp = gitCommitTimestamp
+		d.Commit = gitCommitShortHash
+		d.step("%s isn't tagged, so this is a development version: %d commits since %s, timestamp %s from the %s, commit %s",
+			ref, d.Distance, version, d.Timestamp, dateSource, d.Commit)
+
 		//  The version gets assembled with the pre-release part.
-		version = fmt.Sprintf("%s-%s.%s.%s", version, gitCommitTimestamp, gitNumberCommits, gitCommitShortHash)
+		version = developmentVersion(version, d.Timestamp, d.Distance, d.Commit)
 	}
 
 	// If there's a change in the source tree that didn't get committed, append
 	// `-dirty` to the version string.
-	dirty := false
-	if !force {
-		dirty, err = isDirty()
+	switch {
+	case opts.Force:
+		d.step("the dirty check is skipped, because it's forced")
+	case ref != "HEAD":
+		d.step("the dirty check is skipped, because %s isn't HEAD", ref)
+	default:
+		d.DirtyFiles, err = r.dirtyFiles()
 		if err != nil {
-			return "", err
+			return d, err
+		}
+		d.Dirty = len(d.DirtyFiles) > 0
+		if d.Dirty {
+			d.step("the tree is dirty because of uncommitted changes to %s", strings.Join(d.DirtyFiles, ", "))
+		} else {
+			d.step("the tree is clean")
 		}
 	}
-	if dirty {
+	if d.Dirty {
 		version = fmt.Sprintf("%s-dirty", version)
-		if releaseOnly {
-			return "", errors.New("version is dirty")
+		if opts.ReleaseOnly {
+			return d, errors.WithStack(ErrDirty)
 		}
 	}
 
-	version = strings.TrimPrefix(version, "v")
+	d.Version = strings.TrimPrefix(version, "v")
+
+	return d, nil
+}
+
+// commitTimestamp formats the date of a commit for the pre-release part of a
+// development version, in UTC so it doesn't depend on the time zone of the
+// machine.
+func commitTimestamp(date time.Time) string {
+	return date.UTC().Format("20060102150405")
+}
+
+// developmentVersion returns the version of a commit that isn't tagged, from
+// the closest tag, with the `v` prefix if the tag has one.
+func developmentVersion(tag, timestamp string, distance int, commit string) string {
+	return fmt.Sprintf("%s-%s.%d.%s", tag, timestamp, distance, commit)
+}
+
+// resolve returns the commit of a ref, to run git commands at a commit rather
+// than at an annotated tag. HEAD is returned as is.
+func (r repo) resolve(ref string) (string, error) {
+	if ref == "HEAD" {
+		return ref, nil
+	}
+
+	commit, err := r.git("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil || commit == "" {
+		return "", errorOf(ErrUnknownRef, "'%s' isn't a commit, a branch or a tag of the repository", ref)
+	}
 
-	return version, nil
+	return commit, nil
 }
 
-func isDirty() (bool, error) {
-	status, err := git("status", "--short")
+// lastTag returns the last tag that points to an ancestor of rev, or `0.0.0`
+// if there's none.
+func (r repo) lastTag(rev string) (string, bool, error) {
+	hasTag := true
+	tag, err := r.git("describe", "--tags", "--abbrev=0", rev)
 	if err != nil {
-		return false, errors.Wrap(err, "exec error")
+		// git says "No tags can describe" if no tag is reachable, and "cannot
+		// describe anything" if there's no tag at all.
+		if !strings.Contains(err.Error(), "cannot describe anything") && !strings.Contains(err.Error(), "No tags can describe") {
+			return "", false, errors.Wrap(err, "exec error")
+		} else {
+			tag = "0.0.0"
+			hasTag = false
+		}
 	}
-	return status != "", nil
+
+	if !regexSupportedVersionFormat.MatchString(tag) {
+		return "", false, errors.WithStack(&ErrNotSemver{Tag: tag})
+	}
+
+	return tag, hasTag, nil
+}
+
+// dirtyFiles returns the files with uncommitted changes, including untracked
+// files. A bare repository, like a mirror, has none.
+func (r repo) dirtyFiles() ([]string, error) {
+	bare, err := r.git("rev-parse", "--is-bare-repository")
+	if err != nil {
+		return nil, errors.Wrap(err, "exec error")
+	}
+	if bare == "true" {
+		return []string{}, nil
+	}
+
+	status, err := r.git("status", "--short")
+	if err != nil {
+		return nil, errors.Wrap(err, "exec error")
+	}
+
+	files := []string{}
+	for _, line := range strings.Split(status, "\n") {
+		// Lines are `XY path`, but the output is trimmed, so the first line
+		// may have lost the leading space of an empty X status.
+		if len(line) > 2 {
+			files = append(files, strings.TrimLeft(line[2:], " "))
+		}
+	}
+
+	return files, nil
+}
+
+// newerUnreachableTag returns the highest release tag that's higher than tag
+// but isn't reachable from rev, like a release made on another branch, or an
+// empty string if there's none.
+func (r repo) newerUnreachableTag(tag, rev string) (string, error) {
+	current, err := semver.NewVersion(strings.TrimPrefix(tag, "v"))
+	if err != nil {
+		return "", nil
+	}
+
+	tags, err := r.readTags()
+	if err != nil {
+		return "", err
+	}
+
+	reachable, err := r.reachableTags(rev)
+	if err != nil {
+		return "", err
+	}
+
+	var newer *semver.Version
+	name := ""
+	for _, t := range tags {
+		if strings.Contains(t.name, "/") || !regexSupportedVersionFormat.MatchString(t.name) || reachable[t.name] {
+			continue
+		}
+
+		v, err := semver.NewVersion(strings.TrimPrefix(t.name, "v"))
+		if err != nil || !isRelease(v) || !v.GreaterThan(current) {
+			continue
+		}
+
+		if newer == nil || v.GreaterThan(newer) {
+			newer, name = v, t.name
+		}
+	}
+
+	return name, nil
 }
 
 func PreRelease(currentVersion, identifier string) string {
 	return fmt.Sprintf("%s-%s", currentVersion, identifier)
 }
 
+// NextDerivation records how Next derived the next version.
+type NextDerivation struct {
+	Version string `json:"version"`
+	From    string `json:"from"`
+	Type    string `json:"type"`
+	// Existing is the existing release that moved the patch version further,
+	// or that blocked a minor or major bump.
+	Existing   string   `json:"existing,omitempty"`
+	Dirty      bool     `json:"dirty"`
+	DirtyFiles []string `json:"dirtyFiles"`
+	// Steps describe each decision, in order.
+	Steps []string `json:"steps"`
+}
+
+func (d *NextDerivation) step(format string, args ...interface{}) {
+	d.Steps = append(d.Steps, fmt.Sprintf(format, args...))
+}
+
+// Next returns the next version of a type, 'patch', 'minor' or 'major'. It
+// takes all release tags of the repository into account, so it never returns
+// an existing version: a patch version skips to the next free patch of its
+// minor version, and a minor or major version that conflicts with newer
+// releases, like on a maintenance branch, is an error that explains why.
 func Next(currentVersion, nextType string) (string, error) {
+	d, err := DeriveNext(currentVersion, nextType, Options{})
+	if err != nil {
+		return "", err
+	}
+
+	return d.Version, nil
+}
+
+// DeriveNext works like Next in the repository of opts, and returns the
+// decisions that led to the next version. On error, the derivation holds the
+// decisions made so far. Only the Dir, Git and Ref options are used: the tree
+// is only checked for uncommitted changes at HEAD.
+func DeriveNext(currentVersion, nextType string, opts Options) (*NextDerivation, error) {
+	d := &NextDerivation{From: currentVersion, Type: nextType, DirtyFiles: []string{}, Steps: []string{}}
+
 	major, minor, patch, _, err := Parts(currentVersion)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to get version parts")
+		return d, errors.Wrap(err, "failed to get version parts")
+	}
+
+	r := opts.repo()
+	releases, err := r.releaseTags()
+	if err != nil {
+		return d, err
 	}
 
 	switch nextType {
 	case "patch":
 		patch++
+		d.step("bumping the patch version of %s gives %d.%d.%d", currentVersion, major, minor, patch)
+		var existing *semver.Version
+		for _, release := range releases {
+			if uint64(release.Major()) == major && uint64(release.Minor()) == minor && uint64(release.Patch()) >= patch {
+				patch = uint64(release.Patch()) + 1
+				existing = release
+			}
+		}
+		if existing != nil {
+			d.Existing = existing.String()
+			d.step("version %s already exists, so the next free patch version is %d.%d.%d", existing, major, minor, patch)
+		}
 	case "minor":
 		minor++
 		patch = 0
+		d.step("bumping the minor version of %s gives %d.%d.0", currentVersion, major, minor)
+		if latest := latestRelease(releases, func(v *semver.Version) bool {
+			return uint64(v.Major()) == major && uint64(v.Minor()) >= minor
+		}); latest != nil {
+			d.Existing = latest.String()
+			return d, errorOf(ErrVersionExists, "can't bump the minor version to %d.%d.0: version %s already exists, so this looks like a maintenance line; use 'patch' instead", major, minor, latest)
+		}
+		d.step("no release of %d.%d or later exists yet", major, minor)
 	case "major":
 		major++
 		minor = 0
 		patch = 0
+		d.step("bumping the major version of %s gives %d.0.0", currentVersion, major)
+		if latest := latestRelease(releases, func(v *semver.Version) bool {
+			return uint64(v.Major()) >= major
+		}); latest != nil {
+			d.Existing = latest.String()
+			return d, errorOf(ErrVersionExists, "can't bump the major version to %d.0.0: version %s already exists, so this looks like a maintenance line; use 'minor' or 'patch' instead", major, latest)
+		}
+		d.step("no release of %d or later exists yet", major)
 	default:
-		return "", errors.Errorf("Invalid value '%s' for next version. Supported values are 'patch', 'minor' and 'major'", nextType)
+		return d, errors.Errorf("Invalid value '%s' for next version. Supported values are 'patch', 'minor' and 'major'", nextType)
 	}
 
 	tail := ""
-	dirty, err := isDirty()
-	if err != nil {
-		return "", err
+	if opts.ref() == "HEAD" {
+		d.DirtyFiles, err = r.dirtyFiles()
+		if err != nil {
+			return d, err
+		}
 	}
-	if dirty {
+	d.Dirty = len(d.DirtyFiles) > 0
+	if d.Dirty {
 		tail = "-dirty"
+		d.step("the tree is dirty because of uncommitted changes to %s", strings.Join(d.DirtyFiles, ", "))
 	}
 
-	return fmt.Sprintf("%d.%d.%d%s", major, minor, patch, tail), nil
+	d.Version = fmt.Sprintf("%d.%d.%d%s", major, minor, patch, tail)
+
+	return d, nil
+}
+
+// latestRelease returns the latest of the sorted releases that are in scope, or
+// nil.
+func latestRelease(releases []*semver.Version, inScope func(v *semver.Version) bool) *semver.Version {
+	for i := len(releases) - 1; i >= 0; i-- {
+		if inScope(releases[i]) {
+			return releases[i]
+		}
+	}
+
+	return nil
+}
+
+// releaseTags returns the release versions tagged in the repository, sorted by
+// precedence. Tags of nested Go modules, which contain a `/`, are skipped.
+func (r repo) releaseTags() ([]*semver.Version, error) {
+	tags, err := r.readTags()
+	if err != nil {
+		return nil, err
+	}
+
+	releases := []*semver.Version{}
+	for _, tag := range tags {
+		if strings.Contains(tag.name, "/") || !regexSupportedVersionFormat.MatchString(tag.name) {
+			continue
+		}
+
+		v, err := semver.NewVersion(strings.TrimPrefix(tag.name, "v"))
+		if err != nil || !isRelease(v) {
+			continue
+		}
+
+		releases = append(releases, v)
+	}
+
+	sort.Sort(semver.Collection(releases))
+
+	return releases, nil
 }
 
 func Parts(version string) (uint64, uint64, uint64, string, error) {
@@ -170,7 +511,7 @@ func Parts(version string) (uint64, uint64, uint64, string, error) {
 
 	matches := regexMajor.FindAllStringSubmatch(version, -1)
 	if matches == nil || len(matches) < 1 || len(matches[0]) < 2 {
-		return 0, 0, 0, "", errors.Errorf("'%s' doesn't look like a semver", version)
+		return 0, 0, 0, "", errors.WithStack(&ErrNotSemver{Tag: version})
 	}
 
 	major, err = strconv.ParseUint(matches[0][1], 10, 64)
@@ -180,7 +521,7 @@ func Parts(version string) (uint64, uint64, uint64, string, error) {
 
 	matches = regexMinor.FindAllStringSubmatch(version, -1)
 	if matches == nil || len(matches) < 1 || len(matches[0]) < 2 {
-		return 0, 0, 0, "", errors.Errorf("'%s' doesn't look like a semver", version)
+		return 0, 0, 0, "", errors.WithStack(&ErrNotSemver{Tag: version})
 	}
 
 	minor, err = strconv.ParseUint(matches[0][1], 10, 64)
@@ -190,7 +531,7 @@ func Parts(version string) (uint64, uint64, uint64, string, error) {
 
 	matches = regexPatch.FindAllStringSubmatch(version, -1)
 	if matches == nil || len(matches) < 1 || len(matches[0]) < 2 {
-		return 0, 0, 0, "", errors.Errorf("'%s' doesn't look like a semver", version)
+		return 0, 0, 0, "", errors.WithStack(&ErrNotSemver{Tag: version})
 	}
 
 	patch, err = strconv.ParseUint(matches[0][1], 10, 64)
@@ -200,7 +541,7 @@ func Parts(version string) (uint64, uint64, uint64, string, error) {
 
 	matches = regexTail.FindAllStringSubmatch(version, -1)
 	if matches == nil || len(matches) < 1 || len(matches[0]) < 2 {
-		return 0, 0, 0, "", errors.Errorf("'%s' doesn't look like a semver", version)
+		return 0, 0, 0, "", errors.WithStack(&ErrNotSemver{Tag: version})
 	}
 
 	tail = matches[0][1]
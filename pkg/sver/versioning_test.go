@@ -1,9 +1,13 @@
 package sver_test
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/aserto-dev/sver/pkg/svertest"
+	"github.com/pkg/errors"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -355,6 +359,46 @@ var _ = Describe("sver", func() {
 			})
 		})
 
+		Context("when newer versions exist on other branches", func() {
+			BeforeEach(func() {
+				_, err := git("checkout", "--quiet", "-b", "main")
+				Expect(err).ToNot(HaveOccurred())
+				for _, tag := range []string{"v10.200.6", "v10.201.0", "v11.0.0"} {
+					createCommit(tag)
+					_, err = git("tag", tag)
+					Expect(err).ToNot(HaveOccurred())
+				}
+
+				_, err = git("checkout", "--quiet", "v10.200.5")
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("skips to the next free patch version", func() {
+				version, err := sver.Next("10.200.5", "patch")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(version).To(Equal(`10.200.7`))
+			})
+
+			It("refuses to bump the minor version", func() {
+				_, err := sver.Next("10.200.5", "minor")
+				Expect(err).To(MatchError(ContainSubstring("can't bump the minor version to 10.201.0: version 10.201.0 already exists")))
+			})
+
+			It("refuses to bump the major version", func() {
+				_, err := sver.Next("10.200.5", "major")
+				Expect(err).To(MatchError(ContainSubstring("can't bump the major version to 11.0.0: version 11.0.0 already exists")))
+			})
+
+			It("ignores pre-releases", func() {
+				_, err := git("tag", "v12.0.0-rc.1", "main")
+				Expect(err).ToNot(HaveOccurred())
+
+				version, err := sver.Next("11.0.0", "major")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(version).To(Equal(`12.0.0`))
+			})
+		})
+
 		Context("when the tree is dirty", func() {
 			BeforeEach(func() {
 				createUncomittedChanges()
@@ -394,6 +438,268 @@ var _ = Describe("sver", func() {
 			})
 		})
 	})
+
+	Describe("explaining versions", func() {
+		BeforeEach(func() {
+			createGitDirWithTag("v1.2.0")
+		})
+
+		It("explains a release version", func() {
+			d, err := sver.DeriveVersion(sver.Options{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Version).To(Equal("1.2.0"))
+			Expect(d.Tag).To(Equal("v1.2.0"))
+			Expect(d.OnTag).To(BeTrue())
+			Expect(d.Dirty).To(BeFalse())
+			Expect(d.Steps).To(ContainElement(ContainSubstring("HEAD is tagged with v1.2.0")))
+		})
+
+		It("explains a dirty development version", func() {
+			createCommit("second")
+			createCommit("third")
+			createUncomittedChanges()
+
+			d, err := sver.DeriveVersion(sver.Options{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.OnTag).To(BeFalse())
+			Expect(d.Distance).To(Equal(2))
+			Expect(d.Timestamp).To(MatchRegexp(`^[0-9]{14}$`))
+			Expect(d.Commit).To(MatchRegexp(`^g[0-9a-f]{8}$`))
+			Expect(d.DirtyFiles).To(Equal([]string{"tracked_file"}))
+			Expect(d.Version).To(HavePrefix("1.2.0-" + d.Timestamp + ".2." + d.Commit))
+			Expect(d.Version).To(HaveSuffix("-dirty"))
+		})
+
+		It("explains why a release failed", func() {
+			createCommit("second")
+
+			d, err := sver.DeriveVersion(sver.Options{ReleaseOnly: true})
+			Expect(err).To(HaveOccurred())
+
+			Expect(d.Steps).To(ContainElement("HEAD isn't tagged"))
+		})
+
+		It("mentions higher tags that aren't reachable", func() {
+			_, err := git("checkout", "--quiet", "-b", "side")
+			Expect(err).ToNot(HaveOccurred())
+			createCommit("side")
+			_, err = git("tag", "v1.3.0")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = git("checkout", "--quiet", "v1.2.0")
+			Expect(err).ToNot(HaveOccurred())
+
+			d, err := sver.DeriveVersion(sver.Options{Explain: true})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Steps).To(ContainElement(ContainSubstring("v1.3.0 is a higher version, but it's ignored")))
+
+			d, err = sver.DeriveVersion(sver.Options{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Steps).ToNot(ContainElement(ContainSubstring("v1.3.0 is a higher version")))
+		})
+
+		It("explains a patch version that skips an existing one", func() {
+			_, err := git("checkout", "--quiet", "-b", "side")
+			Expect(err).ToNot(HaveOccurred())
+			createCommit("side")
+			_, err = git("tag", "v1.2.1")
+			Expect(err).ToNot(HaveOccurred())
+
+			d, err := sver.DeriveNext("1.2.0", "patch", sver.Options{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Version).To(Equal("1.2.2"))
+			Expect(d.Existing).To(Equal("1.2.1"))
+		})
+
+		It("names the release that blocks a minor version", func() {
+			_, err := git("tag", "v1.4.0")
+			Expect(err).ToNot(HaveOccurred())
+
+			d, err := sver.DeriveNext("1.2.0", "minor", sver.Options{})
+			Expect(err).To(HaveOccurred())
+
+			Expect(d.Existing).To(Equal("1.4.0"))
+		})
+	})
+})
+
+var _ = Describe("sver in another directory", func() {
+	var repo *svertest.Repo
+
+	BeforeEach(func() {
+		repo = svertest.NewRepo(GinkgoT()).Commit("initial").Tag("v1.2.0")
+	})
+
+	AfterEach(func() {
+		repo.Close()
+	})
+
+	It("derives the version of the repository", func() {
+		d, err := sver.DeriveVersion(repo.Options())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Version).To(Equal("1.2.0"))
+	})
+
+	It("derives a reproducible development version", func() {
+		repo.At(time.Date(2021, time.March, 4, 5, 6, 0, 0, time.UTC)).Commit("fix").Dirty("notes.txt")
+
+		d, err := sver.DeriveVersion(repo.Options())
+		Expect(err).ToNot(HaveOccurred())
+
+		short := repo.Git("rev-parse", "--short=8", "HEAD")
+		Expect(d.Distance).To(Equal(1))
+		Expect(d.Timestamp).To(Equal("20210304050600"))
+		Expect(d.DirtyFiles).To(Equal([]string{"notes.txt"}))
+		Expect(d.Version).To(Equal("1.2.0-20210304050600.1.g" + short + "-dirty"))
+	})
+
+	It("calculates the next version of the repository", func() {
+		repo.Branch("release-1.2").Commit("fix").Tag("v1.2.1").Checkout("main")
+
+		d, err := sver.DeriveNext("1.2.0", "patch", repo.Options())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Version).To(Equal("1.2.2"))
+	})
+
+	It("lists the tags of the repository", func() {
+		tags, err := sver.ListTags(sver.ListOptions{Dir: repo.Dir})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(tags).To(HaveLen(1))
+		Expect(tags[0].Commit).To(Equal(repo.Head()))
+		Expect(tags[0].Date).To(Equal(svertest.Epoch.Format(time.RFC3339)))
+	})
+
+	Context("at a ref", func() {
+		var first string
+
+		BeforeEach(func() {
+			first = repo.Head()
+			repo.Commit("feature").AnnotatedTag("v1.3.0", "release 1.3.0").Commit("fix").Dirty("notes.txt")
+		})
+
+		It("derives the version at a tag", func() {
+			d, err := sver.DeriveVersion(sver.Options{Dir: repo.Dir, Ref: "v1.3.0", ReleaseOnly: true})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Version).To(Equal("1.3.0"))
+			Expect(d.Ref).To(Equal("v1.3.0"))
+			Expect(d.Dirty).To(BeFalse())
+			Expect(d.Steps).To(ContainElement("the dirty check is skipped, because v1.3.0 isn't HEAD"))
+		})
+
+		It("derives the version at a commit", func() {
+			d, err := sver.DeriveVersion(sver.Options{Dir: repo.Dir, Ref: first, Explain: true})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Version).To(Equal("1.2.0"))
+			Expect(d.Steps).To(ContainElement(fmt.Sprintf("v1.3.0 is a higher version, but it's ignored because it isn't reachable from %s", first)))
+		})
+
+		It("derives a development version at a relative ref", func() {
+			repo.Commit("more")
+
+			d, err := sver.DeriveVersion(sver.Options{Dir: repo.Dir, Ref: "HEAD~1"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Distance).To(Equal(1))
+			Expect(d.Version).To(HavePrefix("1.3.0-"))
+			Expect(d.Version).ToNot(HaveSuffix("-dirty"))
+		})
+
+		It("checks HEAD for uncommitted changes", func() {
+			d, err := sver.DeriveVersion(sver.Options{Dir: repo.Dir, Ref: "HEAD"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Dirty).To(BeTrue())
+		})
+
+		It("calculates the next version without checking for uncommitted changes", func() {
+			d, err := sver.DeriveNext("1.3.0", "patch", sver.Options{Dir: repo.Dir, Ref: "v1.3.0"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(d.Version).To(Equal("1.3.1"))
+		})
+
+		It("fails for an unknown ref", func() {
+			_, err := sver.DeriveVersion(sver.Options{Dir: repo.Dir, Ref: "v9.9.9"})
+			Expect(errors.Is(err, sver.ErrUnknownRef)).To(BeTrue())
+			Expect(err.Error()).To(Equal("'v9.9.9' isn't a commit, a branch or a tag of the repository"))
+		})
+	})
+})
+
+var _ = Describe("sver with a fake git backend", func() {
+	It("derives a development version", func() {
+		fake := svertest.NewFakeVersion(svertest.FakeVersion{
+			Tag:        "v1.2.0",
+			Distance:   3,
+			CommitDate: time.Unix(1600000000, 0),
+			ShortHash:  "4fc2e9e5",
+			DirtyFiles: []string{"main.go"},
+		})
+
+		d, err := sver.DeriveVersion(sver.Options{Git: fake})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Version).To(MatchRegexp(`^1\.2\.0-[0-9]{14}\.3\.g4fc2e9e5-dirty$`))
+		Expect(d.DirtyFiles).To(Equal([]string{"main.go"}))
+		Expect(fake.Calls()).To(ContainElement("describe --tags --abbrev=0 HEAD"))
+	})
+
+	It("only looks for unreachable tags when explaining", func() {
+		fake := svertest.NewFakeVersion(svertest.FakeVersion{Tag: "v1.2.0", HeadTags: []string{"v1.2.0"}})
+
+		_, err := sver.DeriveVersion(sver.Options{Git: fake})
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, call := range fake.Calls() {
+			Expect(call).ToNot(HavePrefix("for-each-ref"))
+			Expect(call).ToNot(HavePrefix("tag --merged"))
+		}
+
+		_, err = sver.DeriveVersion(sver.Options{Git: fake, Explain: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fake.Calls()).To(ContainElement("tag --merged HEAD"))
+	})
+
+	It("derives the version at a ref", func() {
+		fake := svertest.NewFakeVersion(svertest.FakeVersion{
+			Ref:        "release-1.2",
+			Tag:        "v1.2.0",
+			Distance:   1,
+			CommitDate: time.Unix(1600000000, 0),
+			ShortHash:  "4fc2e9e5",
+			DirtyFiles: []string{"main.go"},
+		})
+
+		d, err := sver.DeriveVersion(sver.Options{Git: fake, Ref: "release-1.2"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Version).To(MatchRegexp(`^1\.2\.0-[0-9]{14}\.1\.g4fc2e9e5$`))
+		Expect(fake.Calls()).ToNot(ContainElement("status --short"))
+	})
+
+	It("derives a release version", func() {
+		fake := svertest.NewFakeVersion(svertest.FakeVersion{Tag: "v1.2.0", HeadTags: []string{"v1.2.0"}})
+
+		version, err := sver.DeriveVersion(sver.Options{Git: fake, ReleaseOnly: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(version.Version).To(Equal("1.2.0"))
+	})
+
+	It("fails outside of a work tree", func() {
+		_, err := sver.DeriveVersion(sver.Options{Git: svertest.NewFakeGit()})
+		Expect(errors.Is(err, sver.ErrNoGit)).To(BeTrue())
+	})
 })
 
 func createCommit(fileName string) {
@@ -0,0 +1,144 @@
+package svertest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+)
+
+// FakeGit is a sver.GitBackend that answers git commands from a script,
+// without running git. Commands are matched by their arguments joined with
+// spaces, like `describe --tags --abbrev=0`, and unexpected commands fail.
+type FakeGit struct {
+	mu        sync.Mutex
+	responses map[string]fakeResponse
+	calls     []string
+}
+
+type fakeResponse struct {
+	output string
+	err    error
+}
+
+var _ sver.GitBackend = &FakeGit{}
+
+// NewFakeGit returns a fake that answers no command yet, not even the check
+// that the directory is a work tree.
+func NewFakeGit() *FakeGit {
+	return &FakeGit{responses: map[string]fakeResponse{}}
+}
+
+// Set sets the output of a command.
+func (f *FakeGit) Set(command, output string) *FakeGit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses[command] = fakeResponse{output: output}
+
+	return f
+}
+
+// SetError makes a command fail.
+func (f *FakeGit) SetError(command string, err error) *FakeGit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses[command] = fakeResponse{err: err}
+
+	return f
+}
+
+// Git answers a command. The directory is ignored.
+func (f *FakeGit) Git(dir string, args ...string) (string, error) {
+	command := strings.Join(args, " ")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, command)
+
+	response, ok := f.responses[command]
+	if !ok {
+		return "", errors.Errorf("svertest: unexpected git command [%s]", command)
+	}
+
+	return response.output, response.err
+}
+
+// Calls returns the commands run so far, in order.
+func (f *FakeGit) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string{}, f.calls...)
+}
+
+// FakeVersion is the state of a repository, as seen by sver when it derives
+// the current version.
+type FakeVersion struct {
+	// Ref is the ref the version is derived at, the Ref of the options. It
+	// defaults to HEAD. Other refs resolve to ShortHash.
+	Ref string
+	// Tag is the closest tag reachable from Ref, if there is one.
+	Tag string
+	// HeadTags are the tags that point to Ref.
+	HeadTags []string
+	// Distance is the number of commits since Tag.
+	Distance int
+	// CommitDate is the committer date of Ref.
+	CommitDate time.Time
+	// AuthorDate is the author date of Ref. It defaults to CommitDate.
+	AuthorDate time.Time
+	// ShortHash is the abbreviated hash of Ref, 8 characters long.
+	ShortHash string
+	// DirtyFiles are files with uncommitted changes.
+	DirtyFiles []string
+}
+
+// NewFakeVersion returns a fake that answers the commands sver runs to derive
+// the current version of a repository in the given state.
+func NewFakeVersion(v FakeVersion) *FakeGit {
+	f := NewFakeGit()
+	f.Set("rev-parse --is-inside-work-tree", "true")
+	f.Set("rev-parse --is-bare-repository", "false")
+
+	rev := "HEAD"
+	if v.Ref != "" && v.Ref != "HEAD" {
+		rev = v.ShortHash
+		f.Set(fmt.Sprintf("rev-parse --verify --quiet %s^{commit}", v.Ref), rev)
+	}
+
+	f.Set("rev-parse --verify --quiet "+rev, "")
+	f.Set("show --no-patch --format=%ct "+rev, fmt.Sprint(v.CommitDate.Unix()))
+	if v.AuthorDate.IsZero() {
+		v.AuthorDate = v.CommitDate
+	}
+	f.Set("show --no-patch --format=%at "+rev, fmt.Sprint(v.AuthorDate.Unix()))
+	f.Set("rev-parse --short=8 "+rev, v.ShortHash)
+	f.Set("tag --points-at "+rev, strings.Join(v.HeadTags, "\n"))
+
+	status := []string{}
+	for _, file := range v.DirtyFiles {
+		status = append(status, "?? "+file)
+	}
+	f.Set("status --short", strings.Join(status, "\n"))
+
+	if v.Tag == "" {
+		f.SetError("describe --tags --abbrev=0 "+rev, errors.New("fatal: No names found, cannot describe anything."))
+		f.Set("for-each-ref --format=%(refname:strip=2)\x1f%(objectname)\x1f%(*objectname)\x1f%(committerdate:iso-strict)\x1f%(*committerdate:iso-strict) refs/tags", "")
+		f.Set("tag --merged "+rev, "")
+		return f
+	}
+
+	f.Set("describe --tags --abbrev=0 "+rev, v.Tag)
+	f.Set(fmt.Sprintf("rev-list --count %s...%s", v.Tag, rev), fmt.Sprint(v.Distance))
+	f.Set("for-each-ref --format=%(refname:strip=2)\x1f%(objectname)\x1f%(*objectname)\x1f%(committerdate:iso-strict)\x1f%(*committerdate:iso-strict) refs/tags",
+		strings.Join([]string{v.Tag, "", "", "", ""}, "\x1f"))
+	f.Set("tag --merged "+rev, v.Tag)
+
+	return f
+}
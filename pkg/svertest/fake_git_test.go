@@ -0,0 +1,40 @@
+package svertest_test
+
+import (
+	"github.com/aserto-dev/sver/pkg/svertest"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("fake git", func() {
+	It("answers the commands of its script", func() {
+		fake := svertest.NewFakeGit().Set("describe --tags --abbrev=0 HEAD", "v1.2.0")
+
+		out, err := fake.Git("", "describe", "--tags", "--abbrev=0", "HEAD")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal("v1.2.0"))
+	})
+
+	It("fails commands that fail in its script", func() {
+		fake := svertest.NewFakeGit().SetError("status --short", errors.New("fatal: not a git repository"))
+
+		_, err := fake.Git("", "status", "--short")
+		Expect(err).To(MatchError("fatal: not a git repository"))
+	})
+
+	It("fails unexpected commands", func() {
+		_, err := svertest.NewFakeGit().Git("", "rev-parse", "HEAD")
+		Expect(err).To(MatchError("svertest: unexpected git command [rev-parse HEAD]"))
+	})
+
+	It("records the commands in order", func() {
+		fake := svertest.NewFakeGit().Set("rev-parse HEAD", "4fc2e9e5")
+
+		_, _ = fake.Git("", "rev-parse", "HEAD")
+		_, _ = fake.Git("", "status", "--short")
+
+		Expect(fake.Calls()).To(Equal([]string{"rev-parse HEAD", "status --short"}))
+	})
+})
@@ -0,0 +1,78 @@
+package svertest
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Registry is an in-process OCI registry, from the registry package of
+// go-containerregistry. It doesn't need credentials.
+type Registry struct {
+	// Host is the host and port of the registry, like `127.0.0.1:41234`.
+	Host string
+
+	t      TB
+	server *httptest.Server
+}
+
+// NewRegistry starts an empty registry.
+func NewRegistry(t TB) *Registry {
+	t.Helper()
+
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	r := &Registry{
+		Host:   strings.TrimPrefix(server.URL, "http://"),
+		t:      t,
+		server: server,
+	}
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+// Repository returns the full name of a repository of the registry, like
+// `127.0.0.1:41234/org/image`.
+func (r *Registry) Repository(repository string) string {
+	return r.Host + "/" + repository
+}
+
+// PushImage pushes a random image to a repository with the given tags, and
+// returns its digest.
+func (r *Registry) PushImage(repository string, tags ...string) string {
+	r.t.Helper()
+
+	image, err := random.Image(256, 1)
+	if err != nil {
+		r.t.Fatalf("svertest: failed to create an image: %s", err)
+	}
+
+	digest, err := image.Digest()
+	if err != nil {
+		r.t.Fatalf("svertest: failed to get the digest of an image: %s", err)
+	}
+
+	for _, tag := range tags {
+		ref, err := name.NewTag(r.Repository(repository) + ":" + tag)
+		if err != nil {
+			r.t.Fatalf("svertest: invalid tag [%s]: %s", tag, err)
+		}
+
+		if err := remote.Write(ref, image); err != nil {
+			r.t.Fatalf("svertest: failed to push [%s]: %s", ref, err)
+		}
+	}
+
+	return digest.String()
+}
+
+// Close stops the registry. It's safe to call more than once.
+func (r *Registry) Close() {
+	r.server.Close()
+}
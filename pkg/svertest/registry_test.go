@@ -0,0 +1,39 @@
+package svertest_test
+
+import (
+	"github.com/aserto-dev/sver/pkg/svertest"
+	"github.com/google/go-containerregistry/pkg/crane"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("registry", func() {
+	var registry *svertest.Registry
+
+	BeforeEach(func() {
+		registry = svertest.NewRegistry(GinkgoT())
+	})
+
+	AfterEach(func() {
+		registry.Close()
+	})
+
+	It("pushes an image to each tag", func() {
+		digest := registry.PushImage("org/image", "1.0.0", "latest")
+
+		tags, err := crane.ListTags(registry.Repository("org/image"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tags).To(ConsistOf("1.0.0", "latest"))
+
+		for _, tag := range tags {
+			pushed, err := crane.Digest(registry.Repository("org/image") + ":" + tag)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pushed).To(Equal(digest))
+		}
+	})
+
+	It("pushes a different image each time", func() {
+		Expect(registry.PushImage("org/image", "1.0.0")).ToNot(Equal(registry.PushImage("org/image", "1.0.1")))
+	})
+})
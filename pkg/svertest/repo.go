@@ -0,0 +1,206 @@
+package svertest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aserto-dev/sver/pkg/sver"
+	"github.com/pkg/errors"
+)
+
+// Epoch is the date of the first commit of a Repo. Each commit is one minute
+// later than the previous one, so versions are reproducible.
+var Epoch = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Repo is a git repository in a temporary directory, built with chained calls
+// like:
+//
+//	repo := svertest.NewRepo(t).Commit("initial").Tag("v1.0.0").Commit("fix")
+//
+// Commits use a fixed identity and dates, and ignore the git configuration of
+// the host. Any failure is fatal to the test.
+type Repo struct {
+	Dir string
+
+	t     TB
+	clock time.Time
+}
+
+// NewRepo creates an empty repository on the `main` branch.
+func NewRepo(t TB) *Repo {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "svertest")
+	if err != nil {
+		t.Fatalf("svertest: failed to create a repository: %s", err)
+	}
+
+	r := &Repo{Dir: dir, t: t, clock: Epoch}
+	t.Cleanup(r.Close)
+
+	r.Git("init", "--quiet")
+	r.Git("symbolic-ref", "HEAD", "refs/heads/main")
+
+	return r
+}
+
+// Options returns sver options to work on the repository.
+func (r *Repo) Options() sver.Options {
+	return sver.Options{Dir: r.Dir}
+}
+
+// Commit adds an empty commit.
+func (r *Repo) Commit(message string) *Repo {
+	r.t.Helper()
+	r.commit("--allow-empty", "--message", message)
+
+	return r
+}
+
+// CommitFile writes a file, relative to the repository, and commits it.
+func (r *Repo) CommitFile(name, content, message string) *Repo {
+	r.t.Helper()
+	r.WriteFile(name, content)
+	r.Git("add", name)
+	r.commit("--message", message)
+
+	return r
+}
+
+// At sets the date of the next commits. Each commit is still one minute later
+// than the previous one.
+func (r *Repo) At(date time.Time) *Repo {
+	r.clock = date
+
+	return r
+}
+
+// Tag adds a lightweight tag to HEAD.
+func (r *Repo) Tag(name string) *Repo {
+	r.t.Helper()
+	r.Git("tag", name)
+
+	return r
+}
+
+// AnnotatedTag adds an annotated tag to HEAD.
+func (r *Repo) AnnotatedTag(name, message string) *Repo {
+	r.t.Helper()
+	r.Git("tag", "--annotate", "--message", message, name)
+
+	return r
+}
+
+// Branch creates a branch at HEAD and checks it out.
+func (r *Repo) Branch(name string) *Repo {
+	r.t.Helper()
+	r.Git("checkout", "--quiet", "-b", name)
+
+	return r
+}
+
+// Checkout checks out a branch, a tag or a commit.
+func (r *Repo) Checkout(ref string) *Repo {
+	r.t.Helper()
+	r.Git("checkout", "--quiet", ref)
+
+	return r
+}
+
+// Merge merges a branch into the current one with a merge commit.
+func (r *Repo) Merge(branch string) *Repo {
+	r.t.Helper()
+	r.commit("merge", "--no-ff", "--message", "Merge "+branch, branch)
+
+	return r
+}
+
+// Dirty writes a file without committing it, which makes the tree dirty.
+func (r *Repo) Dirty(name string) *Repo {
+	r.t.Helper()
+	r.WriteFile(name, "dirty")
+
+	return r
+}
+
+// WriteFile writes a file, relative to the repository, creating its
+// directory if needed.
+func (r *Repo) WriteFile(name, content string) {
+	r.t.Helper()
+
+	path := filepath.Join(r.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		r.t.Fatalf("svertest: failed to create the directory of %s: %s", name, err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		r.t.Fatalf("svertest: failed to write %s: %s", name, err)
+	}
+}
+
+// Head returns the full hash of HEAD.
+func (r *Repo) Head() string {
+	r.t.Helper()
+
+	return r.Git("rev-parse", "HEAD")
+}
+
+// Git runs git in the repository and returns its trimmed output.
+func (r *Repo) Git(args ...string) string {
+	r.t.Helper()
+
+	out, err := r.run(nil, args...)
+	if err != nil {
+		r.t.Fatalf("svertest: %s", err)
+	}
+
+	return out
+}
+
+// Close removes the repository. It's safe to call more than once.
+func (r *Repo) Close() {
+	os.RemoveAll(r.Dir)
+}
+
+// commit runs `git commit` with args, or another command that commits, like
+// `merge`, if args start with it.
+func (r *Repo) commit(args ...string) {
+	r.t.Helper()
+
+	if strings.HasPrefix(args[0], "--") {
+		args = append([]string{"commit"}, args...)
+	}
+
+	date := r.clock.Format(time.RFC3339)
+	r.clock = r.clock.Add(time.Minute)
+
+	if _, err := r.run([]string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}, args...); err != nil {
+		r.t.Fatalf("svertest: %s", err)
+	}
+}
+
+func (r *Repo) run(env []string, args ...string) (string, error) {
+	config := []string{
+		"-c", "user.name=svertest",
+		"-c", "user.email=svertest@example.com",
+		"-c", "commit.gpgSign=false",
+		"-c", "tag.gpgSign=false",
+		"-c", "core.autocrlf=false",
+	}
+
+	cmd := exec.Command("git", append(config, args...)...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_AUTHOR_NAME=svertest", "GIT_AUTHOR_EMAIL=svertest@example.com",
+		"GIT_COMMITTER_NAME=svertest", "GIT_COMMITTER_EMAIL=svertest@example.com")
+	cmd.Env = append(cmd.Env, env...)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
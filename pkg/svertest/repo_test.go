@@ -0,0 +1,73 @@
+package svertest_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aserto-dev/sver/pkg/svertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("repo", func() {
+	var repo *svertest.Repo
+
+	BeforeEach(func() {
+		repo = svertest.NewRepo(GinkgoT())
+	})
+
+	AfterEach(func() {
+		repo.Close()
+	})
+
+	It("dates commits one minute apart from the epoch", func() {
+		repo.Commit("initial").Commit("feature")
+
+		first := svertest.Epoch.Unix()
+		dates := repo.Git("log", "--format=%ct %at")
+		Expect(strings.Split(dates, "\n")).To(Equal([]string{
+			fmt.Sprintf("%d %d", first+60, first+60),
+			fmt.Sprintf("%d %d", first, first),
+		}))
+	})
+
+	It("dates the next commits", func() {
+		repo.Commit("initial").At(time.Date(2021, time.March, 4, 5, 6, 0, 0, time.UTC)).Commit("fix").Commit("fix")
+
+		Expect(repo.Git("log", "-1", "--format=%ct")).To(Equal(fmt.Sprint(time.Date(2021, time.March, 4, 5, 7, 0, 0, time.UTC).Unix())))
+	})
+
+	It("creates and checks out branches", func() {
+		repo.Commit("initial").Branch("topic").Commit("topic")
+
+		Expect(repo.Git("branch", "--show-current")).To(Equal("topic"))
+
+		repo.Checkout("main")
+
+		Expect(repo.Git("branch", "--show-current")).To(Equal("main"))
+		Expect(repo.Git("rev-list", "--count", "HEAD")).To(Equal("1"))
+	})
+
+	It("merges a branch with a merge commit", func() {
+		repo.Commit("initial").Branch("topic").Commit("topic").Checkout("main").Merge("topic")
+
+		Expect(repo.Git("log", "-1", "--format=%s")).To(Equal("Merge topic"))
+		Expect(strings.Fields(repo.Git("log", "-1", "--format=%P"))).To(HaveLen(2))
+		Expect(repo.Git("log", "-1", "--format=%ct")).To(Equal(fmt.Sprint(svertest.Epoch.Add(2 * time.Minute).Unix())))
+	})
+
+	It("tags HEAD", func() {
+		repo.Commit("initial").Tag("v1.0.0").AnnotatedTag("v1.0.1", "release 1.0.1")
+
+		Expect(repo.Git("tag", "--points-at", "HEAD")).To(Equal("v1.0.0\nv1.0.1"))
+		Expect(repo.Git("cat-file", "-t", "v1.0.1")).To(Equal("tag"))
+	})
+
+	It("makes the tree dirty", func() {
+		repo.Commit("initial").Dirty("notes.txt")
+
+		Expect(repo.Git("status", "--short")).To(Equal("?? notes.txt"))
+	})
+})
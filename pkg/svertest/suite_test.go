@@ -0,0 +1,13 @@
+package svertest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSvertest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "svertest suite")
+}
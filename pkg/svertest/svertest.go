@@ -0,0 +1,16 @@
+// Package svertest provides fixtures to test code that uses sver without
+// depending on the state of the host: git repositories built in temporary
+// directories, a fake git backend, and an in-process OCI registry.
+//
+// Fixtures don't change the current directory, so tests that use them can run
+// in parallel. Pass the directory of a repository to sver with Repo.Options.
+package svertest
+
+// TB is the part of testing.TB that fixtures use. Both *testing.T and
+// Ginkgo's GinkgoT() implement it. Fixtures are closed with Cleanup, but
+// Ginkgo v1 ignores it, so Ginkgo tests should call Close themselves.
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+	Cleanup(func())
+}
@@ -0,0 +1,4 @@
+// nolint: testpackage / export private state to test
+package version
+
+var InfoFrom = infoFrom
@@ -3,7 +3,8 @@ package version
 import (
 	"fmt"
 	"runtime"
-	"time"
+	"runtime/debug"
+	"strings"
 )
 
 // values set by linker using ldflag -X.
@@ -15,37 +16,82 @@ var (
 
 // Info - version info.
 type Info struct {
-	Version string
-	Date    string
-	Commit  string
+	Version   string `json:"version"`
+	Date      string `json:"date"`
+	Commit    string `json:"commit"`
+	Dirty     bool   `json:"dirty"`
+	GoVersion string `json:"goVersion"`
+	Path      string `json:"path"`
 }
 
 // GetInfo gets version stamp information.
+// Values that weren't set by the linker are read from the build info that the
+// go command embeds in binaries, like for `go install module@version`.
 func GetInfo() Info {
-	if ver == "" {
-		ver = "0.0.0"
+	buildInfo, _ := debug.ReadBuildInfo()
+
+	return infoFrom(Info{
+		Version:   ver,
+		Date:      date,
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+	}, buildInfo)
+}
+
+// infoFrom completes the values set by the linker with the build info, which
+// may be nil, and fills in defaults for what's still missing.
+func infoFrom(info Info, buildInfo *debug.BuildInfo) Info {
+	if buildInfo != nil {
+		info.Path = buildInfo.Main.Path
+		if buildInfo.GoVersion != "" {
+			info.GoVersion = buildInfo.GoVersion
+		}
+
+		if info.Version == "" && buildInfo.Main.Version != "" && buildInfo.Main.Version != "(devel)" {
+			info.Version = strings.TrimPrefix(buildInfo.Main.Version, "v")
+		}
+
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				info.Dirty = setting.Value == "true"
+			}
+		}
 	}
 
-	if date == "" {
-		date = time.Now().Format(time.RFC3339)
+	if info.Version == "" {
+		info.Version = "0.0.0"
 	}
 
-	if commit == "" {
-		commit = "undefined"
+	if info.Date == "" {
+		info.Date = "undefined"
 	}
 
-	return Info{
-		Version: ver,
-		Date:    date,
-		Commit:  commit,
+	if info.Commit == "" {
+		info.Commit = "undefined"
 	}
+
+	return info
 }
 
 // String() returns the version info string.
 func (vi Info) String() string {
+	commit := vi.Commit
+	if vi.Dirty {
+		commit += "-dirty"
+	}
+
 	return fmt.Sprintf("%s g%s %s-%s [%s]",
 		vi.Version,
-		vi.Commit,
+		commit,
 		runtime.GOOS,
 		runtime.GOARCH,
 		vi.Date,
@@ -0,0 +1,71 @@
+package version_test
+
+import (
+	"runtime/debug"
+
+	"github.com/aserto-dev/sver/pkg/version"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("version info", func() {
+	vcs := []debug.BuildSetting{
+		{Key: "vcs", Value: "git"},
+		{Key: "vcs.revision", Value: "4f0c6a1e9d2b"},
+		{Key: "vcs.time", Value: "2021-03-04T05:06:07Z"},
+		{Key: "vcs.modified", Value: "true"},
+	}
+
+	buildInfo := func(version string, settings []debug.BuildSetting) *debug.BuildInfo {
+		return &debug.BuildInfo{
+			GoVersion: "go1.19.4",
+			Main:      debug.Module{Path: "github.com/aserto-dev/sver", Version: version},
+			Settings:  settings,
+		}
+	}
+
+	DescribeTable("maps the build info",
+		func(linked version.Info, bi *debug.BuildInfo, expected version.Info) {
+			Expect(version.InfoFrom(linked, bi)).To(Equal(expected))
+		},
+		Entry("without build info",
+			version.Info{GoVersion: "go1.20"},
+			nil,
+			version.Info{Version: "0.0.0", Date: "undefined", Commit: "undefined", GoVersion: "go1.20"},
+		),
+		Entry("of go install module@version",
+			version.Info{},
+			buildInfo("v1.2.3", nil),
+			version.Info{
+				Version: "1.2.3", Date: "undefined", Commit: "undefined",
+				GoVersion: "go1.19.4", Path: "github.com/aserto-dev/sver",
+			},
+		),
+		Entry("of a development build with vcs settings",
+			version.Info{},
+			buildInfo("(devel)", vcs),
+			version.Info{
+				Version: "0.0.0", Date: "2021-03-04T05:06:07Z", Commit: "4f0c6a1e9d2b", Dirty: true,
+				GoVersion: "go1.19.4", Path: "github.com/aserto-dev/sver",
+			},
+		),
+		Entry("of a clean checkout",
+			version.Info{},
+			buildInfo("v1.2.3", []debug.BuildSetting{{Key: "vcs.revision", Value: "4f0c6a1e9d2b"}, {Key: "vcs.modified", Value: "false"}}),
+			version.Info{
+				Version: "1.2.3", Date: "undefined", Commit: "4f0c6a1e9d2b",
+				GoVersion: "go1.19.4", Path: "github.com/aserto-dev/sver",
+			},
+		),
+		Entry("with values set by the linker",
+			version.Info{Version: "1.4.0", Date: "2022-01-02T03:04:05Z", Commit: "a1b2c3d4"},
+			buildInfo("v1.2.3", vcs),
+			version.Info{
+				Version: "1.4.0", Date: "2022-01-02T03:04:05Z", Commit: "a1b2c3d4", Dirty: true,
+				GoVersion: "go1.19.4", Path: "github.com/aserto-dev/sver",
+			},
+		),
+	)
+})